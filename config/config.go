@@ -1,6 +1,9 @@
 package config
 
 import (
+	"errors"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -15,7 +18,26 @@ type Config struct {
 	WorkerEnabled         bool
 	DirectoryPath         string
 	JobName               string
+	DedupWindow           time.Duration
+	ChecksumTTL           time.Duration
+	ShutdownTimeout       time.Duration
+	FileLeaseTTL          time.Duration
+	MaxUploadBytes        int64
+	RedisMode             string
 	StandaloneRedisConfig *standaloneRedisConfig
+	SentinelRedisConfig   *sentinelRedisConfig
+	FrequencyCapConfig    *frequencyCapConfig
+	FileLifecycleConfig   *fileLifecycleConfig
+	FileQueueConfig       *fileQueueConfig
+	FileChunkConfig       *fileChunkConfig
+	RecipientIDConfig     *recipientIDConfig
+	EnrichmentConfig      *enrichmentConfig
+	SegmentConfig         *segmentConfig
+	ManifestConfig        *manifestConfig
+	SignatureConfig       *signatureConfig
+	EncryptionConfig      *encryptionConfig
+	WorkerPoolConfig      *workerPoolConfig
+	EnqueueConfig         *enqueueConfig
 }
 
 var AppConfig *Config
@@ -35,14 +57,42 @@ func LoadAndGetConfig() (*Config, error) {
 		return nil, err
 	}
 	viper.AutomaticEnv()
+	jobName := getStringWithDefault("JOB_NAME", "send_message")
+	redisMode := getRedisMode()
+	var sentinelConfig *sentinelRedisConfig
+	if redisMode == RedisModeSentinel {
+		sentinelConfig = newSentinelRedisConfig()
+	}
 	AppConfig = &Config{
 		HTTPServerPort:        getStringWithDefault("HTTP_SERVER_PORT", "8080"),
 		ServiceName:           serviceName,
 		LogLevel:              getStringWithDefault("LOG_LEVEL", "info"),
-		WorkerEnabled:         getBoolWithDefault("WORKER_ENABLED", true),
+		WorkerEnabled:         getBoolWithDefault("WORKER_ENABLED", false),
 		DirectoryPath:         getStringOrPanic("DIRECTORY_PATH"),
-		JobName:               getStringWithDefault("JOB_NAME", "send_message"),
-		StandaloneRedisConfig: newStandaloneRedisConfig(),
+		JobName:               jobName,
+		DedupWindow:           time.Minute * time.Duration(getIntWithDefault("DEDUP_WINDOW_MINUTES", 24*60)),
+		ChecksumTTL:           time.Hour * time.Duration(getIntWithDefault("CHECKSUM_TTL_HOURS", 7*24)),
+		ShutdownTimeout:       time.Second * time.Duration(getIntWithDefault("SHUTDOWN_TIMEOUT_SECONDS", 25)),
+		FileLeaseTTL:          time.Second * time.Duration(getIntWithDefault("FILE_LEASE_TTL_SECONDS", 30)),
+		MaxUploadBytes:        int64(getIntWithDefault("VALIDATE_MAX_UPLOAD_MB", 100)) << 20,
+		RedisMode:             redisMode,
+		StandaloneRedisConfig: newStandaloneRedisConfig(redisMode),
+		SentinelRedisConfig:   sentinelConfig,
+		FrequencyCapConfig:    newFrequencyCapConfig(),
+		FileLifecycleConfig:   newFileLifecycleConfig(),
+		FileQueueConfig:       newFileQueueConfig(),
+		FileChunkConfig:       newFileChunkConfig(),
+		RecipientIDConfig:     newRecipientIDConfig(),
+		EnrichmentConfig:      newEnrichmentConfig(),
+		SegmentConfig:         newSegmentConfig(),
+		ManifestConfig:        newManifestConfig(),
+		SignatureConfig:       newSignatureConfig(),
+		EncryptionConfig:      newEncryptionConfig(),
+		WorkerPoolConfig:      newWorkerPoolConfig(jobName),
+		EnqueueConfig:         newEnqueueConfig(),
+	}
+	if AppConfig.WorkerPoolConfig.DrainTimeout >= AppConfig.ShutdownTimeout {
+		return nil, errors.New("WORKER_DRAIN_TIMEOUT_SECONDS must be shorter than SHUTDOWN_TIMEOUT_SECONDS")
 	}
 	return AppConfig, nil
 }
@@ -0,0 +1,13 @@
+package config
+
+// encryptionConfig holds the keys dropped files encrypted with age are decrypted with.
+type encryptionConfig struct {
+	// IdentitiesPath is the age identity file holding the private keys, empty not to accept encrypted files
+	IdentitiesPath string
+}
+
+func newEncryptionConfig() *encryptionConfig {
+	return &encryptionConfig{
+		IdentitiesPath: getStringWithDefault("AGE_IDENTITIES_PATH", ""),
+	}
+}
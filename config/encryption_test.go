@@ -0,0 +1,27 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestNewEncryptionConfig(t *testing.T) {
+	// setup
+	os.Setenv("AGE_IDENTITIES_PATH", "/run/secrets/swilly-age-identities")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("AGE_IDENTITIES_PATH")
+	}()
+
+	config := newEncryptionConfig()
+
+	// verify
+	expected := &encryptionConfig{
+		IdentitiesPath: "/run/secrets/swilly-age-identities",
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expected)
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+// enqueueConfig holds how the server enqueues jobs and copes with redis being unavailable meanwhile.
+type enqueueConfig struct {
+	// BatchSize is how many jobs of a file are enqueued together in one round trip, 1 to enqueue them
+	// one by one
+	BatchSize int
+	// FlushInterval is how long the jobs of a file may wait for their batch to fill up
+	FlushInterval time.Duration
+	// Retries is how many times a job is enqueued again, waiting twice as long each time from
+	// RetryBackoff, before it's spooled to disk
+	Retries      int
+	RetryBackoff time.Duration
+	// SpoolPath is the local append-only file spooled jobs are written to
+	SpoolPath string
+	// ReplayInterval is how often spooled jobs are pushed to redis again
+	ReplayInterval time.Duration
+}
+
+func newEnqueueConfig() *enqueueConfig {
+	batchSize := getIntWithDefault("ENQUEUE_BATCH_SIZE", 500)
+	if batchSize < 1 {
+		log.Fatalf("ENQUEUE_BATCH_SIZE must be at least 1")
+	}
+
+	return &enqueueConfig{
+		BatchSize:      batchSize,
+		FlushInterval:  time.Millisecond * time.Duration(getIntWithDefault("ENQUEUE_FLUSH_INTERVAL_MS", 1000)),
+		Retries:        getIntWithDefault("ENQUEUE_RETRIES", 3),
+		RetryBackoff:   time.Millisecond * time.Duration(getIntWithDefault("ENQUEUE_RETRY_BACKOFF_MS", 100)),
+		SpoolPath:      getStringWithDefault("SPOOL_PATH", "spool/jobs.jsonl"),
+		ReplayInterval: time.Second * time.Duration(getIntWithDefault("SPOOL_REPLAY_INTERVAL_SECONDS", 10)),
+	}
+}
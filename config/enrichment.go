@@ -0,0 +1,45 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	InactivePolicyDrop = "drop"
+	InactivePolicyFlag = "flag"
+)
+
+// enrichmentConfig holds how user IDs are enriched with the profile of the user before being enqueued.
+type enrichmentConfig struct {
+	// ProfileAPIURL is the base URL of the user-profile API, empty to skip enrichment
+	ProfileAPIURL string
+	Timeout       time.Duration
+	// BatchSize is how many users of a file are looked up together, in the user-profile API as well as in the
+	// suppression list and dedup state
+	BatchSize int
+	// CacheTTL is how long profiles are cached in redis
+	CacheTTL time.Duration
+	// InactivePolicy tells whether users whose account is inactive or unknown are dropped, or enqueued
+	// flagged as inactive
+	InactivePolicy string
+}
+
+func newEnrichmentConfig() *enrichmentConfig {
+	batchSize := getIntWithDefault("ENRICHMENT_BATCH_SIZE", 100)
+	if batchSize < 1 {
+		log.Fatalf("ENRICHMENT_BATCH_SIZE must be at least 1")
+	}
+	policy := getStringWithDefault("ENRICHMENT_INACTIVE_POLICY", InactivePolicyDrop)
+	if policy != InactivePolicyDrop && policy != InactivePolicyFlag {
+		log.Fatalf("ENRICHMENT_INACTIVE_POLICY must be one of %s, %s", InactivePolicyDrop, InactivePolicyFlag)
+	}
+
+	return &enrichmentConfig{
+		ProfileAPIURL:  getStringWithDefault("PROFILE_API_URL", ""),
+		Timeout:        time.Millisecond * time.Duration(getIntWithDefault("PROFILE_API_TIMEOUT_MS", 2000)),
+		BatchSize:      batchSize,
+		CacheTTL:       time.Second * time.Duration(getIntWithDefault("PROFILE_CACHE_TTL_SECONDS", 3600)),
+		InactivePolicy: policy,
+	}
+}
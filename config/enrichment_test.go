@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewEnrichmentConfig(t *testing.T) {
+	// setup
+	os.Setenv("PROFILE_API_URL", "http://profiles.internal")
+	os.Setenv("ENRICHMENT_BATCH_SIZE", "50")
+	os.Setenv("PROFILE_CACHE_TTL_SECONDS", "600")
+	os.Setenv("ENRICHMENT_INACTIVE_POLICY", "flag")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("PROFILE_API_URL")
+		os.Unsetenv("ENRICHMENT_BATCH_SIZE")
+		os.Unsetenv("PROFILE_CACHE_TTL_SECONDS")
+		os.Unsetenv("ENRICHMENT_INACTIVE_POLICY")
+	}()
+
+	config := newEnrichmentConfig()
+
+	// verify
+	expected := &enrichmentConfig{
+		ProfileAPIURL:  "http://profiles.internal",
+		Timeout:        2 * time.Second,
+		BatchSize:      50,
+		CacheTTL:       10 * time.Minute,
+		InactivePolicy: InactivePolicyFlag,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expected)
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import "log"
+
+// fileChunkConfig holds how large files are split to process their lines in parallel.
+type fileChunkConfig struct {
+	// Size is the number of bytes above which a file is split into chunks of about that size, each
+	// starting and ending on a line boundary, 0 to never split files
+	Size int64
+	// Workers is how many chunks of a file are processed at once
+	Workers int
+}
+
+func newFileChunkConfig() *fileChunkConfig {
+	size := getIntWithDefault("FILE_CHUNK_SIZE_BYTES", 64<<20)
+	if size < 0 {
+		log.Fatalf("FILE_CHUNK_SIZE_BYTES must not be negative")
+	}
+	workers := getIntWithDefault("FILE_CHUNK_WORKERS", 4)
+	if workers < 1 {
+		log.Fatalf("FILE_CHUNK_WORKERS must be at least 1")
+	}
+
+	return &fileChunkConfig{
+		Size:    int64(size),
+		Workers: workers,
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestNewFileChunkConfig(t *testing.T) {
+	// setup
+	os.Setenv("FILE_CHUNK_SIZE_BYTES", "1048576")
+	os.Setenv("FILE_CHUNK_WORKERS", "8")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("FILE_CHUNK_SIZE_BYTES")
+		os.Unsetenv("FILE_CHUNK_WORKERS")
+	}()
+
+	config := newFileChunkConfig()
+
+	// verify
+	expected := &fileChunkConfig{
+		Size:    1048576,
+		Workers: 8,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expected)
+	}
+}
@@ -0,0 +1,16 @@
+package config
+
+// fileLifecycleConfig holds the thresholds deciding which folder a file lands in once processed.
+type fileLifecycleConfig struct {
+	// Files with a larger share of invalid lines are quarantined without enqueueing anything
+	QuarantineInvalidPercent float64
+	// Files with a larger share of lines that could not be enqueued are moved to the failed folder
+	FailedEnqueuePercent float64
+}
+
+func newFileLifecycleConfig() *fileLifecycleConfig {
+	return &fileLifecycleConfig{
+		QuarantineInvalidPercent: getFloatWithDefault("QUARANTINE_INVALID_PERCENT", 5),
+		FailedEnqueuePercent:     getFloatWithDefault("FAILED_ENQUEUE_PERCENT", 0),
+	}
+}
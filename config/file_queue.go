@@ -0,0 +1,68 @@
+package config
+
+import (
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileQueueConfig holds how many files are processed at once and in which order the others wait.
+type fileQueueConfig struct {
+	Workers int
+	// Priorities rank the pending files by name, the first matching pattern giving the priority of a
+	// file. Files of equal priority are processed in order of arrival.
+	Priorities []FilePriority
+	// HighWaterMark is the number of jobs waiting in the job queue above which no new file is picked
+	// up, 0 to pick files up regardless
+	HighWaterMark int64
+	// BackpressureInterval is how often the job queue is checked while above the high-water mark
+	BackpressureInterval time.Duration
+	// ProcessTimeout is how long a worker spends on a file before checkpointing it for a later run to
+	// carry on, 0 for no limit
+	ProcessTimeout time.Duration
+}
+
+// FilePriority gives the files whose name matches the glob Pattern their Priority, higher first.
+type FilePriority struct {
+	Pattern  string
+	Priority int
+}
+
+func newFileQueueConfig() *fileQueueConfig {
+	workers := getIntWithDefault("FILE_WORKERS", 4)
+	if workers < 1 {
+		log.Fatalf("FILE_WORKERS must be at least 1")
+	}
+
+	return &fileQueueConfig{
+		Workers:              workers,
+		Priorities:           parseFilePriorities(getStringWithDefault("FILE_PRIORITIES", "")),
+		HighWaterMark:        int64(getIntWithDefault("QUEUE_HIGH_WATER_MARK", 100000)),
+		BackpressureInterval: time.Millisecond * time.Duration(getIntWithDefault("BACKPRESSURE_CHECK_INTERVAL_MS", 1000)),
+		ProcessTimeout:       time.Second * time.Duration(getIntWithDefault("FILE_PROCESS_TIMEOUT_SECONDS", 0)),
+	}
+}
+
+// parseFilePriorities reads priorities written as comma separated pattern=priority pairs, e.g.
+// "urgent_*=10,swilly_*=1".
+func parseFilePriorities(value string) []FilePriority {
+	var priorities []FilePriority
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		pattern, rawPriority, found := strings.Cut(pair, "=")
+		priority, err := strconv.Atoi(strings.TrimSpace(rawPriority))
+		if !found || err != nil {
+			log.Fatalf("FILE_PRIORITIES must be comma separated pattern=priority pairs, got %q", pair)
+		}
+		pattern = strings.TrimSpace(pattern)
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			log.Fatalf("FILE_PRIORITIES has an invalid pattern %q: %v", pattern, err)
+		}
+		priorities = append(priorities, FilePriority{Pattern: pattern, Priority: priority})
+	}
+	return priorities
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewFileQueueConfig(t *testing.T) {
+	// setup
+	os.Setenv("FILE_WORKERS", "8")
+	os.Setenv("FILE_PRIORITIES", "urgent_* = 10, swilly_*=1")
+	os.Setenv("QUEUE_HIGH_WATER_MARK", "5000")
+	os.Setenv("FILE_PROCESS_TIMEOUT_SECONDS", "600")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("FILE_WORKERS")
+		os.Unsetenv("FILE_PRIORITIES")
+		os.Unsetenv("QUEUE_HIGH_WATER_MARK")
+		os.Unsetenv("FILE_PROCESS_TIMEOUT_SECONDS")
+	}()
+
+	config := newFileQueueConfig()
+
+	// verify
+	expected := &fileQueueConfig{
+		Workers: 8,
+		Priorities: []FilePriority{
+			{Pattern: "urgent_*", Priority: 10},
+			{Pattern: "swilly_*", Priority: 1},
+		},
+		HighWaterMark:        5000,
+		BackpressureInterval: time.Second,
+		ProcessTimeout:       10 * time.Minute,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expected)
+	}
+}
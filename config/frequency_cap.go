@@ -0,0 +1,30 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	FrequencyCapPolicyDrop  = "drop"
+	FrequencyCapPolicyDefer = "defer"
+)
+
+type frequencyCapConfig struct {
+	Limit  int
+	Window time.Duration
+	Policy string
+}
+
+func newFrequencyCapConfig() *frequencyCapConfig {
+	policy := getStringWithDefault("FREQUENCY_CAP_POLICY", FrequencyCapPolicyDrop)
+	if policy != FrequencyCapPolicyDrop && policy != FrequencyCapPolicyDefer {
+		log.Fatalf("FREQUENCY_CAP_POLICY must be one of %s, %s", FrequencyCapPolicyDrop, FrequencyCapPolicyDefer)
+	}
+
+	return &frequencyCapConfig{
+		Limit:  getIntWithDefault("FREQUENCY_CAP_LIMIT", 0),
+		Window: time.Minute * time.Duration(getIntWithDefault("FREQUENCY_CAP_WINDOW_MINUTES", 24*60)),
+		Policy: policy,
+	}
+}
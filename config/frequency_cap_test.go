@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewFrequencyCapConfig(t *testing.T) {
+	// setup
+	os.Setenv("FREQUENCY_CAP_LIMIT", "3")
+	os.Setenv("FREQUENCY_CAP_WINDOW_MINUTES", "60")
+	os.Setenv("FREQUENCY_CAP_POLICY", "defer")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("FREQUENCY_CAP_LIMIT")
+		os.Unsetenv("FREQUENCY_CAP_WINDOW_MINUTES")
+		os.Unsetenv("FREQUENCY_CAP_POLICY")
+	}()
+
+	config := newFrequencyCapConfig()
+
+	// verify
+	expectedConfig := &frequencyCapConfig{
+		Limit:  3,
+		Window: time.Hour,
+		Policy: FrequencyCapPolicyDefer,
+	}
+
+	if *config != *expectedConfig {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expectedConfig)
+	}
+}
@@ -0,0 +1,22 @@
+package config
+
+import "time"
+
+// manifestConfig holds how the manifests describing dropped files are waited for and checked.
+type manifestConfig struct {
+	// Wait is how long a dropped file waits for its manifest before being processed, 0 not to wait
+	Wait time.Duration
+	// Required fails the files still without a manifest once the wait is over
+	Required bool
+	// RowCountTolerancePercent is how far, as a share of the expected row count, the line count of a
+	// file may be from the manifest's before the file is quarantined
+	RowCountTolerancePercent float64
+}
+
+func newManifestConfig() *manifestConfig {
+	return &manifestConfig{
+		Wait:                     time.Second * time.Duration(getIntWithDefault("MANIFEST_WAIT_SECONDS", 0)),
+		Required:                 getBoolWithDefault("MANIFEST_REQUIRED", false),
+		RowCountTolerancePercent: getFloatWithDefault("MANIFEST_ROW_COUNT_TOLERANCE_PERCENT", 0),
+	}
+}
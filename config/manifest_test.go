@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewManifestConfig(t *testing.T) {
+	// setup
+	os.Setenv("MANIFEST_WAIT_SECONDS", "300")
+	os.Setenv("MANIFEST_REQUIRED", "true")
+	os.Setenv("MANIFEST_ROW_COUNT_TOLERANCE_PERCENT", "2.5")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("MANIFEST_WAIT_SECONDS")
+		os.Unsetenv("MANIFEST_REQUIRED")
+		os.Unsetenv("MANIFEST_ROW_COUNT_TOLERANCE_PERCENT")
+	}()
+
+	config := newManifestConfig()
+
+	// verify
+	expected := &manifestConfig{
+		Wait:                     5 * time.Minute,
+		Required:                 true,
+		RowCountTolerancePercent: 2.5,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expected)
+	}
+}
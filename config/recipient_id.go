@@ -0,0 +1,49 @@
+package config
+
+import (
+	"log"
+	"strings"
+)
+
+const (
+	RecipientIDRuleInt    = "int"
+	RecipientIDRuleUUID   = "uuid"
+	RecipientIDRuleRegex  = "regex"
+	RecipientIDRulePrefix = "prefix"
+)
+
+// recipientIDConfig holds the rule recipient IDs read from files must follow.
+type recipientIDConfig struct {
+	Rule string
+	// Min and Max bound the IDs of the int rule, empty for no bound
+	Min string
+	Max string
+	// Pattern is the regular expression IDs of the regex rule must match as a whole
+	Pattern string
+	// Prefixes are the prefixes allowed by the prefix rule
+	Prefixes []string
+}
+
+func newRecipientIDConfig() *recipientIDConfig {
+	rule := getStringWithDefault("RECIPIENT_ID_RULE", RecipientIDRuleInt)
+	switch rule {
+	case RecipientIDRuleInt, RecipientIDRuleUUID, RecipientIDRuleRegex, RecipientIDRulePrefix:
+	default:
+		log.Fatalf("RECIPIENT_ID_RULE must be one of %s, %s, %s, %s", RecipientIDRuleInt, RecipientIDRuleUUID, RecipientIDRuleRegex, RecipientIDRulePrefix)
+	}
+
+	var prefixes []string
+	for _, prefix := range strings.Split(getStringWithDefault("RECIPIENT_ID_PREFIXES", ""), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	return &recipientIDConfig{
+		Rule:     rule,
+		Min:      getStringWithDefault("RECIPIENT_ID_MIN", ""),
+		Max:      getStringWithDefault("RECIPIENT_ID_MAX", ""),
+		Pattern:  getStringWithDefault("RECIPIENT_ID_PATTERN", ""),
+		Prefixes: prefixes,
+	}
+}
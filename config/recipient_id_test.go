@@ -0,0 +1,30 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestNewRecipientIDConfig(t *testing.T) {
+	// setup
+	os.Setenv("RECIPIENT_ID_RULE", "prefix")
+	os.Setenv("RECIPIENT_ID_PREFIXES", "u_, guest_")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("RECIPIENT_ID_RULE")
+		os.Unsetenv("RECIPIENT_ID_PREFIXES")
+	}()
+
+	config := newRecipientIDConfig()
+
+	// verify
+	expected := &recipientIDConfig{
+		Rule:     RecipientIDRulePrefix,
+		Prefixes: []string{"u_", "guest_"},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expected)
+	}
+}
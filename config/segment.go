@@ -0,0 +1,44 @@
+package config
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	SegmentResolverNone = ""
+	SegmentResolverHTTP = "http"
+	SegmentResolverFile = "file"
+)
+
+// segmentConfig holds where the members of the segments listed in segment files come from.
+type segmentConfig struct {
+	// Resolver is http to ask the segment API, file to read the segment store, empty to fail segment files
+	Resolver string
+	APIURL   string
+	// APITimeout bounds the wait for the segment API to start responding
+	APITimeout time.Duration
+	// StorePath is the directory holding a file of member IDs per segment
+	StorePath string
+}
+
+func newSegmentConfig() *segmentConfig {
+	resolver := getStringWithDefault("SEGMENT_RESOLVER", SegmentResolverNone)
+	apiURL := getStringWithDefault("SEGMENT_API_URL", "")
+	storePath := getStringWithDefault("SEGMENT_STORE_PATH", "")
+	switch {
+	case resolver != SegmentResolverNone && resolver != SegmentResolverHTTP && resolver != SegmentResolverFile:
+		log.Fatalf("SEGMENT_RESOLVER must be empty or one of %s, %s", SegmentResolverHTTP, SegmentResolverFile)
+	case resolver == SegmentResolverHTTP && apiURL == "":
+		log.Fatalf("SEGMENT_API_URL is required by the %s segment resolver", SegmentResolverHTTP)
+	case resolver == SegmentResolverFile && storePath == "":
+		log.Fatalf("SEGMENT_STORE_PATH is required by the %s segment resolver", SegmentResolverFile)
+	}
+
+	return &segmentConfig{
+		Resolver:   resolver,
+		APIURL:     apiURL,
+		APITimeout: time.Millisecond * time.Duration(getIntWithDefault("SEGMENT_API_TIMEOUT_MS", 5000)),
+		StorePath:  storePath,
+	}
+}
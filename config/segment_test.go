@@ -0,0 +1,32 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewSegmentConfig(t *testing.T) {
+	// setup
+	os.Setenv("SEGMENT_RESOLVER", "file")
+	os.Setenv("SEGMENT_STORE_PATH", "/data/segments")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("SEGMENT_RESOLVER")
+		os.Unsetenv("SEGMENT_STORE_PATH")
+	}()
+
+	config := newSegmentConfig()
+
+	// verify
+	expected := &segmentConfig{
+		Resolver:   SegmentResolverFile,
+		APITimeout: 5 * time.Second,
+		StorePath:  "/data/segments",
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expected)
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"log"
+	"strings"
+)
+
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+)
+
+// sentinelRedisConfig locates the master through redis sentinel. Connections to the master are
+// otherwise set up as configured in standaloneRedisConfig, apart from its host.
+type sentinelRedisConfig struct {
+	Addrs      []string
+	MasterName string
+	// Password authenticates with the sentinels, which may differ from the master's
+	Password string
+}
+
+func getRedisMode() string {
+	mode := getStringWithDefault("REDIS_MODE", RedisModeStandalone)
+	if mode != RedisModeStandalone && mode != RedisModeSentinel {
+		log.Fatalf("REDIS_MODE must be one of %s, %s", RedisModeStandalone, RedisModeSentinel)
+	}
+	return mode
+}
+
+func newSentinelRedisConfig() *sentinelRedisConfig {
+	var addrs []string
+	for _, addr := range strings.Split(getStringOrPanic("SENTINEL_REDIS_ADDRS"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	return &sentinelRedisConfig{
+		Addrs:      addrs,
+		MasterName: getStringOrPanic("SENTINEL_REDIS_MASTER_NAME"),
+		Password:   getStringWithDefault("SENTINEL_REDIS_PASSWORD", ""),
+	}
+}
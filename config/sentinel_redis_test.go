@@ -0,0 +1,31 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestNewSentinelRedisConfig(t *testing.T) {
+	// setup
+	os.Setenv("SENTINEL_REDIS_ADDRS", "sentinel-0:26379, sentinel-1:26379,")
+	os.Setenv("SENTINEL_REDIS_MASTER_NAME", "delivery")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("SENTINEL_REDIS_ADDRS")
+		os.Unsetenv("SENTINEL_REDIS_MASTER_NAME")
+	}()
+
+	config := newSentinelRedisConfig()
+
+	// verify
+	expectedConfig := &sentinelRedisConfig{
+		Addrs:      []string{"sentinel-0:26379", "sentinel-1:26379"},
+		MasterName: "delivery",
+	}
+
+	if !reflect.DeepEqual(config, expectedConfig) {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expectedConfig)
+	}
+}
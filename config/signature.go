@@ -0,0 +1,18 @@
+package config
+
+import "time"
+
+// signatureConfig holds which keys dropped files must be signed with.
+type signatureConfig struct {
+	// KeysPath is the keyring of the approved public keys, empty not to check signatures
+	KeysPath string
+	// Wait is how long a dropped file waits for its signature before being quarantined as unsigned
+	Wait time.Duration
+}
+
+func newSignatureConfig() *signatureConfig {
+	return &signatureConfig{
+		KeysPath: getStringWithDefault("SIGNING_KEYS_PATH", ""),
+		Wait:     time.Second * time.Duration(getIntWithDefault("SIGNATURE_WAIT_SECONDS", 60)),
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewSignatureConfig(t *testing.T) {
+	// setup
+	os.Setenv("SIGNING_KEYS_PATH", "/etc/swilly/signers")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("SIGNING_KEYS_PATH")
+	}()
+
+	config := newSignatureConfig()
+
+	// verify
+	expected := &signatureConfig{
+		KeysPath: "/etc/swilly/signers",
+		Wait:     time.Minute,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expected)
+	}
+}
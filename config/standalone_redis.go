@@ -8,12 +8,45 @@ type standaloneRedisConfig struct {
 	RedisHost        string
 	RedisPoolSize    int
 	RedisPoolTimeout time.Duration
+	// RedisUsername is the ACL user, leave empty to authenticate with the password only
+	RedisUsername string
+	RedisPassword string
+	RedisDB       int
+	RedisTLS      bool
+	// RedisTLSCACert is the path of a PEM file with the CA to trust instead of the system ones
+	RedisTLSCACert      string
+	RedisConnectTimeout time.Duration
+	RedisReadTimeout    time.Duration
+	RedisWriteTimeout   time.Duration
+	// RedisHealthCheckInterval is how long a pooled connection may stay idle before it's pinged when
+	// borrowed, 0 to ping every time
+	RedisHealthCheckInterval time.Duration
+	// RedisKeyPrefix namespaces the keys of the service, apart from the gocraft queues which live
+	// under WORKER_NAMESPACE. The two must not overlap.
+	RedisKeyPrefix string
 }
 
-func newStandaloneRedisConfig() *standaloneRedisConfig {
+// newStandaloneRedisConfig reads the redis connection settings. The host is only required in standalone
+// mode, in sentinel mode it's looked up through the sentinels.
+func newStandaloneRedisConfig(mode string) *standaloneRedisConfig {
+	host := getStringWithDefault("STANDALONE_REDIS_HOST", "")
+	if mode == RedisModeStandalone {
+		host = getStringOrPanic("STANDALONE_REDIS_HOST")
+	}
+
 	return &standaloneRedisConfig{
-		RedisHost:        getStringOrPanic("STANDALONE_REDIS_HOST"),
-		RedisPoolSize:    getIntOrPanic("STANDALONE_REDIS_POOL_SIZE"),
-		RedisPoolTimeout: time.Millisecond * time.Duration(getIntOrPanic("STANDALONE_REDIS_POOL_TIMEOUT_MS")),
+		RedisHost:                host,
+		RedisPoolSize:            getIntOrPanic("STANDALONE_REDIS_POOL_SIZE"),
+		RedisPoolTimeout:         time.Millisecond * time.Duration(getIntOrPanic("STANDALONE_REDIS_POOL_TIMEOUT_MS")),
+		RedisUsername:            getStringWithDefault("STANDALONE_REDIS_USERNAME", ""),
+		RedisPassword:            getStringWithDefault("STANDALONE_REDIS_PASSWORD", ""),
+		RedisDB:                  getIntWithDefault("STANDALONE_REDIS_DB", 0),
+		RedisTLS:                 getBoolWithDefault("STANDALONE_REDIS_TLS", false),
+		RedisTLSCACert:           getStringWithDefault("STANDALONE_REDIS_TLS_CA_CERT", ""),
+		RedisConnectTimeout:      time.Millisecond * time.Duration(getIntWithDefault("STANDALONE_REDIS_CONNECT_TIMEOUT_MS", 1000)),
+		RedisReadTimeout:         time.Millisecond * time.Duration(getIntWithDefault("STANDALONE_REDIS_READ_TIMEOUT_MS", 1000)),
+		RedisWriteTimeout:        time.Millisecond * time.Duration(getIntWithDefault("STANDALONE_REDIS_WRITE_TIMEOUT_MS", 1000)),
+		RedisHealthCheckInterval: time.Millisecond * time.Duration(getIntWithDefault("STANDALONE_REDIS_HEALTH_CHECK_INTERVAL_MS", 60000)),
+		RedisKeyPrefix:           getStringWithDefault("STANDALONE_REDIS_KEY_PREFIX", "delivery-service:"),
 	}
 }
@@ -4,8 +4,42 @@ import (
 	"os"
 	"testing"
 	"time"
+
+	"github.com/spf13/viper"
 )
 
+func TestNewStandaloneRedisConfig_Auth(t *testing.T) {
+	// setup
+	os.Setenv("STANDALONE_REDIS_HOST", "redis.internal:6380")
+	os.Setenv("STANDALONE_REDIS_POOL_SIZE", "10")
+	os.Setenv("STANDALONE_REDIS_POOL_TIMEOUT_MS", "100")
+	os.Setenv("STANDALONE_REDIS_USERNAME", "delivery")
+	os.Setenv("STANDALONE_REDIS_PASSWORD", "secret")
+	os.Setenv("STANDALONE_REDIS_DB", "2")
+	os.Setenv("STANDALONE_REDIS_TLS", "true")
+	os.Setenv("STANDALONE_REDIS_TLS_CA_CERT", "/etc/redis/ca.pem")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("STANDALONE_REDIS_HOST")
+		os.Unsetenv("STANDALONE_REDIS_POOL_SIZE")
+		os.Unsetenv("STANDALONE_REDIS_POOL_TIMEOUT_MS")
+		os.Unsetenv("STANDALONE_REDIS_USERNAME")
+		os.Unsetenv("STANDALONE_REDIS_PASSWORD")
+		os.Unsetenv("STANDALONE_REDIS_DB")
+		os.Unsetenv("STANDALONE_REDIS_TLS")
+		os.Unsetenv("STANDALONE_REDIS_TLS_CA_CERT")
+	}()
+
+	config := newStandaloneRedisConfig(RedisModeStandalone)
+
+	// verify
+	if config.RedisUsername != "delivery" || config.RedisPassword != "secret" || config.RedisDB != 2 ||
+		!config.RedisTLS || config.RedisTLSCACert != "/etc/redis/ca.pem" {
+		t.Errorf("Configuration mismatch. Got: %v", config)
+	}
+}
+
 func TestNewStandaloneRedisConfig(t *testing.T) {
 	// setup
 	os.Setenv("STANDALONE_REDIS_HOST", "localhost:6379")
@@ -13,7 +47,6 @@ func TestNewStandaloneRedisConfig(t *testing.T) {
 	os.Setenv("STANDALONE_REDIS_POOL_TIMEOUT_MS", "100")
 	os.Setenv("STANDALONE_REDIS_READ_TIMEOUT_MS", "200")
 	os.Setenv("STANDALONE_REDIS_WRITE_TIMEOUT_MS", "300")
-	os.Setenv("STANDALONE_REDIS_KEY_PREFIX", "delivery:")
 
 	defer func() {
 		// cleanup
@@ -22,19 +55,46 @@ func TestNewStandaloneRedisConfig(t *testing.T) {
 		os.Unsetenv("STANDALONE_REDIS_POOL_TIMEOUT_MS")
 		os.Unsetenv("STANDALONE_REDIS_READ_TIMEOUT_MS")
 		os.Unsetenv("STANDALONE_REDIS_WRITE_TIMEOUT_MS")
-		os.Unsetenv("STANDALONE_REDIS_KEY_PREFIX")
 	}()
 
-	config := newStandaloneRedisConfig()
+	config := newStandaloneRedisConfig(RedisModeStandalone)
 
 	// verify
 	expectedConfig := &standaloneRedisConfig{
-		RedisHost:        "localhost:6379",
-		RedisPoolSize:    10,
-		RedisPoolTimeout: 100 * time.Millisecond,
+		RedisHost:                "localhost:6379",
+		RedisPoolSize:            10,
+		RedisPoolTimeout:         100 * time.Millisecond,
+		RedisConnectTimeout:      time.Second,
+		RedisReadTimeout:         200 * time.Millisecond,
+		RedisWriteTimeout:        300 * time.Millisecond,
+		RedisHealthCheckInterval: time.Minute,
+		RedisKeyPrefix:           "delivery-service:",
 	}
 
 	if *config != *expectedConfig {
 		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config, expectedConfig)
 	}
 }
+
+func TestNewStandaloneRedisConfig_SentinelWithoutHost(t *testing.T) {
+	// setup, as if the host was left out of the config file too
+	os.Setenv("STANDALONE_REDIS_POOL_SIZE", "10")
+	os.Setenv("STANDALONE_REDIS_POOL_TIMEOUT_MS", "100")
+	host := viper.Get("STANDALONE_REDIS_HOST")
+	viper.Set("STANDALONE_REDIS_HOST", "")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("STANDALONE_REDIS_POOL_SIZE")
+		os.Unsetenv("STANDALONE_REDIS_POOL_TIMEOUT_MS")
+		viper.Set("STANDALONE_REDIS_HOST", host)
+	}()
+
+	// Doesn't exit for the missing host, the master being looked up through the sentinels
+	config := newStandaloneRedisConfig(RedisModeSentinel)
+
+	// verify
+	if config.RedisHost != "" || config.RedisPoolSize != 10 {
+		t.Errorf("Configuration mismatch. Got: %v", config)
+	}
+}
@@ -38,6 +38,19 @@ func getIntWithDefault(key string, defaultValue int) int {
 	return intVal
 }
 
+func getFloatWithDefault(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		value = viper.GetString(key)
+	}
+
+	floatVal, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return floatVal
+}
+
 func getStringOrPanic(key string) string {
 	checkKey(key)
 	value := os.Getenv(key)
@@ -19,6 +19,12 @@ func TestGetIntWithDefault(t *testing.T) {
 	assert.Equal(t, getIntWithDefault(key, 100), 100)
 }
 
+func TestGetFloatWithDefault(t *testing.T) {
+	key := "FLOAT_DEFAULT"
+	os.Unsetenv(key)
+	assert.Equal(t, getFloatWithDefault(key, 2.5), 2.5)
+}
+
 func TestGetBoolWithDefault(t *testing.T) {
 	key := "BOOL_DEFAULT"
 	os.Unsetenv(key)
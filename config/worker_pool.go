@@ -0,0 +1,100 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// BackoffDefault is the gocraft backoff, growing with the fourth power of the number of fails
+	BackoffDefault     = "default"
+	BackoffExponential = "exponential"
+)
+
+const (
+	QueueBackendRedis = "redis"
+	// QueueBackendMemory keeps the jobs in the process, for local development and tests
+	QueueBackendMemory = "memory"
+)
+
+// workerPoolConfig holds the settings of the job queue, shared by the server enqueueing
+// jobs and the worker running them.
+type workerPoolConfig struct {
+	Backend     string
+	Namespace   string
+	Concurrency int
+	// DrainTimeout is how long the worker waits for running jobs on shutdown, shorter than the shutdown
+	// timeout so that it's done draining before the process is stopped
+	DrainTimeout time.Duration
+	// Jobs holds the options of every known job, each defaulting to the pool wide WORKER_* settings
+	// unless overridden with WORKER_JOB_<JOB NAME>_* keys
+	Jobs map[string]*jobConfig
+}
+
+// jobConfig holds the options of a single job.
+type jobConfig struct {
+	// MaxConcurrency caps the number of jobs of this name running at once across all workers, 0 for no cap
+	MaxConcurrency int
+	MaxFails       int
+	// Priority weighs how often jobs of this name are fetched compared to others, from 1 to 100000
+	Priority    int
+	Backoff     string
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+func newWorkerPoolConfig(jobNames ...string) *workerPoolConfig {
+	defaults := newJobConfig("WORKER_", &jobConfig{
+		MaxConcurrency: 0,
+		MaxFails:       3,
+		Priority:       1,
+		Backoff:        BackoffDefault,
+		BackoffBase:    10 * time.Second,
+		BackoffMax:     time.Hour,
+	})
+
+	jobs := make(map[string]*jobConfig, len(jobNames))
+	for _, name := range jobNames {
+		jobs[name] = newJobConfig("WORKER_JOB_"+jobKey(name)+"_", defaults)
+	}
+
+	backend := getStringWithDefault("QUEUE_BACKEND", QueueBackendRedis)
+	if backend != QueueBackendRedis && backend != QueueBackendMemory {
+		log.Fatalf("QUEUE_BACKEND must be one of %s, %s", QueueBackendRedis, QueueBackendMemory)
+	}
+
+	return &workerPoolConfig{
+		Backend:      backend,
+		Namespace:    getStringWithDefault("WORKER_NAMESPACE", "delivery"),
+		Concurrency:  getIntWithDefault("WORKER_CONCURRENCY", 10),
+		DrainTimeout: time.Second * time.Duration(getIntWithDefault("WORKER_DRAIN_TIMEOUT_SECONDS", 20)),
+		Jobs:         jobs,
+	}
+}
+
+func newJobConfig(prefix string, defaults *jobConfig) *jobConfig {
+	backoff := getStringWithDefault(prefix+"BACKOFF", defaults.Backoff)
+	if backoff != BackoffDefault && backoff != BackoffExponential {
+		log.Fatalf("%sBACKOFF must be one of %s, %s", prefix, BackoffDefault, BackoffExponential)
+	}
+
+	return &jobConfig{
+		MaxConcurrency: getIntWithDefault(prefix+"MAX_CONCURRENCY", defaults.MaxConcurrency),
+		MaxFails:       getIntWithDefault(prefix+"MAX_FAILS", defaults.MaxFails),
+		Priority:       getIntWithDefault(prefix+"PRIORITY", defaults.Priority),
+		Backoff:        backoff,
+		BackoffBase:    time.Second * time.Duration(getIntWithDefault(prefix+"BACKOFF_BASE_SECONDS", int(defaults.BackoffBase/time.Second))),
+		BackoffMax:     time.Second * time.Duration(getIntWithDefault(prefix+"BACKOFF_MAX_SECONDS", int(defaults.BackoffMax/time.Second))),
+	}
+}
+
+// jobKey turns a job name into the form it takes in config keys, e.g. send_message into SEND_MESSAGE.
+func jobKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(name))
+}
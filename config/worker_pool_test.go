@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestNewWorkerPoolConfig(t *testing.T) {
+	// setup
+	os.Setenv("QUEUE_BACKEND", "memory")
+	os.Setenv("WORKER_NAMESPACE", "delivery_staging")
+	os.Setenv("WORKER_CONCURRENCY", "25")
+	os.Setenv("WORKER_DRAIN_TIMEOUT_SECONDS", "15")
+	os.Setenv("WORKER_MAX_FAILS", "5")
+	os.Setenv("WORKER_JOB_SEND_MESSAGE_MAX_CONCURRENCY", "4")
+	os.Setenv("WORKER_JOB_SEND_MESSAGE_PRIORITY", "10")
+	os.Setenv("WORKER_JOB_SEND_MESSAGE_BACKOFF", "exponential")
+	os.Setenv("WORKER_JOB_SEND_MESSAGE_BACKOFF_MAX_SECONDS", "600")
+
+	defer func() {
+		// cleanup
+		os.Unsetenv("QUEUE_BACKEND")
+		os.Unsetenv("WORKER_NAMESPACE")
+		os.Unsetenv("WORKER_CONCURRENCY")
+		os.Unsetenv("WORKER_DRAIN_TIMEOUT_SECONDS")
+		os.Unsetenv("WORKER_MAX_FAILS")
+		os.Unsetenv("WORKER_JOB_SEND_MESSAGE_MAX_CONCURRENCY")
+		os.Unsetenv("WORKER_JOB_SEND_MESSAGE_PRIORITY")
+		os.Unsetenv("WORKER_JOB_SEND_MESSAGE_BACKOFF")
+		os.Unsetenv("WORKER_JOB_SEND_MESSAGE_BACKOFF_MAX_SECONDS")
+	}()
+
+	config := newWorkerPoolConfig("send_message", "send-digest")
+
+	// verify
+	if config.Backend != QueueBackendMemory || config.Namespace != "delivery_staging" || config.Concurrency != 25 ||
+		config.DrainTimeout != 15*time.Second {
+		t.Errorf("Pool configuration mismatch. Got: %v", config)
+	}
+
+	expectedSendMessage := jobConfig{
+		MaxConcurrency: 4,
+		MaxFails:       5,
+		Priority:       10,
+		Backoff:        BackoffExponential,
+		BackoffBase:    10 * time.Second,
+		BackoffMax:     10 * time.Minute,
+	}
+	if *config.Jobs["send_message"] != expectedSendMessage {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config.Jobs["send_message"], expectedSendMessage)
+	}
+
+	expectedSendDigest := jobConfig{
+		MaxConcurrency: 0,
+		MaxFails:       5,
+		Priority:       1,
+		Backoff:        BackoffDefault,
+		BackoffBase:    10 * time.Second,
+		BackoffMax:     time.Hour,
+	}
+	if *config.Jobs["send-digest"] != expectedSendDigest {
+		t.Errorf("Configuration mismatch. Got: %v, Expected: %v", config.Jobs["send-digest"], expectedSendDigest)
+	}
+}
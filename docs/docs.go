@@ -23,7 +23,449 @@ var doc = `{
     },
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
-    "paths": {}
+    "paths": {
+        "/files/{name}/manifest": {
+            "get": {
+                "description": "Returns the manifest describing the campaign of a file, whether it's waiting, being processed or done with.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Manifest of a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/manifest.Manifest"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{name}/stats": {
+            "get": {
+                "description": "Returns the ingestion and delivery counters recorded for a processed file.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Processing counters of a file",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/suppressions": {
+            "post": {
+                "description": "Adds user IDs to the global opt-out list. Suppressed users are never messaged. User IDs are normalized like those of files, and rejected if they don't follow the recipient ID rule.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "suppressions"
+                ],
+                "summary": "Suppress user IDs",
+                "parameters": [
+                    {
+                        "description": "User IDs to suppress",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/server.suppressionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.suppressionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Removes user IDs from the global opt-out list.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "suppressions"
+                ],
+                "summary": "Lift suppression for user IDs",
+                "parameters": [
+                    {
+                        "description": "User IDs to remove",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/server.suppressionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.suppressionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/suppressions/import": {
+            "post": {
+                "description": "Adds every user ID in the request body, one per line, to the global opt-out list. The import stops at the first invalid user ID.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "suppressions"
+                ],
+                "summary": "Bulk import suppressed user IDs",
+                "parameters": [
+                    {
+                        "description": "Newline separated user IDs",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.suppressionResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/validate": {
+            "post": {
+                "description": "Runs the uploaded file through the ingestion pipeline as a dry run and returns the counts of valid, invalid, duplicate and suppressed recipients, sample jobs and the report the real run would write. The file is either the request body, its sidecars being passed as parameters, or the ` + "`" + `file` + "`" + ` part of a multipart/form-data request, its sidecars being the ` + "`" + `manifest` + "`" + `, ` + "`" + `signature` + "`" + ` and ` + "`" + `manifest_signature` + "`" + ` parts. A manifest starting with ` + "`" + `{` + "`" + ` is read as JSON, as YAML otherwise.",
+                "consumes": [
+                    "text/plain",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Validate a file without sending anything",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "File name, as it would be dropped in the directory",
+                        "name": "name",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Manifest of the file, as in its .manifest.json or .manifest.yaml file",
+                        "name": "manifest",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Detached signature of the file, as in its .sig file, when files must be signed",
+                        "name": "signature",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Detached signature of the manifest, when files must be signed",
+                        "name": "manifest_signature",
+                        "in": "query"
+                    },
+                    {
+                        "description": "File content",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/server.validationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/server.errorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "manifest.Manifest": {
+            "type": "object",
+            "properties": {
+                "campaign": {
+                    "type": "string"
+                },
+                "channel": {
+                    "type": "string"
+                },
+                "expected_rows": {
+                    "description": "ExpectedRows is the number of lines of the file",
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when the campaign is no longer worth sending, if ever",
+                    "type": "string"
+                },
+                "owner": {
+                    "description": "Owner is who to reach about the campaign",
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "integer"
+                },
+                "resend": {
+                    "description": "Resend is set when the file is deliberately sent again, to users the campaign was already sent to",
+                    "type": "boolean"
+                },
+                "send_at": {
+                    "description": "SendAt is when the campaign is meant to be sent, if scheduled",
+                    "type": "string"
+                },
+                "template": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.errorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "server.fileSummary": {
+            "type": "object",
+            "properties": {
+                "duplicates": {
+                    "type": "integer"
+                },
+                "encrypted": {
+                    "description": "Encrypted is set when the file was encrypted, in which case its report leaves out the rejected user IDs",
+                    "type": "boolean"
+                },
+                "enqueued": {
+                    "type": "integer"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "filename": {
+                    "type": "string"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "inactive": {
+                    "description": "Inactive counts the users dropped because their account is inactive or unknown",
+                    "type": "integer"
+                },
+                "invalid": {
+                    "type": "integer"
+                },
+                "lines": {
+                    "type": "integer"
+                },
+                "manifest": {
+                    "description": "Manifest describes the campaign of the file, if it came with one",
+                    "$ref": "#/definitions/manifest.Manifest"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "signed_by": {
+                    "description": "SignedBy is the identity of the approved key the file was signed with, if signatures are required",
+                    "type": "string"
+                },
+                "spooled": {
+                    "description": "Spooled counts the jobs written to the local spool while redis was unavailable",
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "suppressed": {
+                    "type": "integer"
+                }
+            }
+        },
+        "server.suppressionRequest": {
+            "type": "object",
+            "properties": {
+                "user_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "server.suppressionResponse": {
+            "type": "object",
+            "properties": {
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "server.validationResponse": {
+            "type": "object",
+            "properties": {
+                "report": {
+                    "type": "string"
+                },
+                "sample_jobs": {
+                    "type": "array",
+                    "items": {
+                        "type": "object",
+                        "additionalProperties": true
+                    }
+                },
+                "summary": {
+                    "$ref": "#/definitions/server.fileSummary"
+                }
+            }
+        }
+    }
 }`
 
 type swaggerInfo struct {
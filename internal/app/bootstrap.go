@@ -1,15 +1,44 @@
 package app
 
 import (
+	"fmt"
+	"strings"
 	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/pkg/checksum"
+	"swilly-delivery-service/internal/pkg/dedup"
+	"swilly-delivery-service/internal/pkg/encryption"
+	"swilly-delivery-service/internal/pkg/frequency"
+	"swilly-delivery-service/internal/pkg/lease"
 	"swilly-delivery-service/internal/pkg/log"
+	"swilly-delivery-service/internal/pkg/profile"
+	"swilly-delivery-service/internal/pkg/queue"
+	"swilly-delivery-service/internal/pkg/recipient"
 	redisclient "swilly-delivery-service/internal/pkg/redis"
-
-	"github.com/gomodule/redigo/redis"
+	"swilly-delivery-service/internal/pkg/segment"
+	"swilly-delivery-service/internal/pkg/signature"
+	"swilly-delivery-service/internal/pkg/stats"
+	"swilly-delivery-service/internal/pkg/suppression"
 )
 
 type Dependency struct {
-	Redis *redis.Pool
+	// Redis is nil when the stores and job queue are kept in memory
+	Redis       *redisclient.Pool
+	Suppression suppression.List
+	Stats       stats.Store
+	Frequency   frequency.Limiter
+	Dedup       dedup.Tracker
+	Checksums   checksum.Store
+	Leases      lease.Locker
+	Queue       queue.Backend
+	Recipients  recipient.Validator
+	// Profiles is nil unless enrichment is enabled
+	Profiles profile.Client
+	// Segments is nil unless a segment resolver is configured
+	Segments segment.Resolver
+	// Signers is nil unless dropped files must be signed
+	Signers *signature.Keyring
+	// Decrypter is nil unless dropped files may be encrypted
+	Decrypter *encryption.Decrypter
 }
 
 var AppDependency *Dependency
@@ -20,9 +49,111 @@ func Bootstrap() error {
 	}
 	log.SetLogLevel(config.AppConfig.LogLevel)
 
-	AppDependency = &Dependency{
-		Redis: redisclient.NewRedisPool(),
+	var err error
+	if config.AppConfig.WorkerPoolConfig.Backend == config.QueueBackendMemory {
+		AppDependency = newMemoryDependency()
+	} else if AppDependency, err = newRedisDependency(); err != nil {
+		return err
+	}
+	if AppDependency.Recipients, err = newRecipientValidator(); err != nil {
+		return fmt.Errorf("invalid recipient ID rule: %w", err)
+	}
+	AppDependency.Suppression = suppression.NewValidatingList(AppDependency.Suppression, AppDependency.Recipients)
+	if enrichment := config.AppConfig.EnrichmentConfig; enrichment.ProfileAPIURL != "" {
+		AppDependency.Profiles = profile.NewHTTPClient(enrichment.ProfileAPIURL, enrichment.Timeout)
+		if AppDependency.Redis != nil {
+			prefix := config.AppConfig.StandaloneRedisConfig.RedisKeyPrefix
+			AppDependency.Profiles = profile.NewCachedClient(AppDependency.Profiles, AppDependency.Redis.Pool, prefix, enrichment.CacheTTL)
+		}
+	}
+	switch segments := config.AppConfig.SegmentConfig; segments.Resolver {
+	case config.SegmentResolverHTTP:
+		AppDependency.Segments = segment.NewHTTPResolver(segments.APIURL, segments.APITimeout)
+	case config.SegmentResolverFile:
+		AppDependency.Segments = segment.NewFileResolver(segments.StorePath)
+	}
+	if keysPath := config.AppConfig.SignatureConfig.KeysPath; keysPath != "" {
+		if AppDependency.Signers, err = signature.LoadKeyring(keysPath); err != nil {
+			return fmt.Errorf("invalid signing keys: %w", err)
+		}
+	}
+	if identitiesPath := config.AppConfig.EncryptionConfig.IdentitiesPath; identitiesPath != "" {
+		if AppDependency.Decrypter, err = encryption.LoadDecrypter(identitiesPath); err != nil {
+			return fmt.Errorf("invalid age identities: %w", err)
+		}
 	}
 
 	return nil
 }
+
+// newRedisDependency returns the stores and job queue kept in redis, shared by every instance of the service.
+func newRedisDependency() (*Dependency, error) {
+	prefix := config.AppConfig.StandaloneRedisConfig.RedisKeyPrefix
+	frequencyCap := config.AppConfig.FrequencyCapConfig
+	poolConfig := config.AppConfig.WorkerPoolConfig
+	if strings.HasPrefix(prefix, strings.TrimSuffix(poolConfig.Namespace, ":")+":") {
+		return nil, fmt.Errorf("key prefix %q overlaps the worker namespace %q", prefix, poolConfig.Namespace)
+	}
+	pool, err := redisclient.NewRedisPool()
+	if err != nil {
+		return nil, err
+	}
+	return &Dependency{
+		Redis:       pool,
+		Suppression: suppression.NewRedisList(pool.Pool, prefix),
+		Stats:       stats.NewRedisStore(pool.Pool, prefix),
+		Frequency:   frequency.NewRedisLimiter(pool.Pool, prefix, frequencyCap.Limit, frequencyCap.Window),
+		Dedup:       dedup.NewRedisTracker(pool.Pool, prefix, config.AppConfig.DedupWindow),
+		Checksums:   checksum.NewRedisStore(pool.Pool, prefix, config.AppConfig.ChecksumTTL),
+		Leases:      lease.NewRedisLocker(pool.Pool, prefix, config.AppConfig.FileLeaseTTL),
+		Queue:       queue.NewGocraftBackend(pool.Pool, poolConfig.Namespace, uint(poolConfig.Concurrency)),
+	}, nil
+}
+
+// newMemoryDependency returns the stores and job queue kept in memory, for running the whole flow in a
+// single process without redis. Their state is lost when the process exits.
+func newMemoryDependency() *Dependency {
+	frequencyCap := config.AppConfig.FrequencyCapConfig
+	return &Dependency{
+		Suppression: suppression.NewMemoryList(),
+		Stats:       stats.NewMemoryStore(),
+		Frequency:   frequency.NewMemoryLimiter(frequencyCap.Limit, frequencyCap.Window),
+		Dedup:       dedup.NewMemoryTracker(config.AppConfig.DedupWindow),
+		Checksums:   checksum.NewMemoryStore(config.AppConfig.ChecksumTTL),
+		Leases:      lease.NewMemoryLocker(config.AppConfig.FileLeaseTTL),
+		Queue:       queue.NewMemoryBackend(uint(config.AppConfig.WorkerPoolConfig.Concurrency)),
+	}
+}
+
+// newRecipientValidator returns the validator of the configured recipient ID rule.
+func newRecipientValidator() (recipient.Validator, error) {
+	recipientID := config.AppConfig.RecipientIDConfig
+	switch recipientID.Rule {
+	case config.RecipientIDRuleUUID:
+		return recipient.NewUUIDValidator(), nil
+	case config.RecipientIDRuleRegex:
+		return recipient.NewRegexValidator(recipientID.Pattern)
+	case config.RecipientIDRulePrefix:
+		return recipient.NewPrefixValidator(recipientID.Prefixes)
+	default:
+		return recipient.NewIntValidator(recipientID.Min, recipientID.Max)
+	}
+}
+
+// DryRun returns a copy of the dependencies that reads but never records dedup and checksum state,
+// for validating files without affecting later runs.
+func (d *Dependency) DryRun() *Dependency {
+	prefix := config.AppConfig.StandaloneRedisConfig.RedisKeyPrefix
+	dryRun := *d
+	if tracker, ok := d.Dedup.(*dedup.MemoryTracker); ok {
+		dryRun.Dedup = tracker.DryRun()
+	} else {
+		dryRun.Dedup = dedup.NewDryRunTracker(d.Redis.Pool, prefix, config.AppConfig.DedupWindow)
+	}
+	if store, ok := d.Checksums.(*checksum.MemoryStore); ok {
+		dryRun.Checksums = store.DryRun()
+	} else {
+		dryRun.Checksums = checksum.NewDryRunStore(d.Redis.Pool, prefix)
+	}
+	return &dryRun
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/pkg/log"
+	"sync"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// Service is a long running part of the application, such as the server or the worker.
+type Service interface {
+	// Start starts the service in the background.
+	Start(ctx context.Context) error
+	// Stop stops the service, giving up on whatever is left to do when ctx is done.
+	Stop(ctx context.Context) error
+}
+
+// Run starts the services in order and, on SIGINT or SIGTERM or once ctx is done, stops them before
+// closing the redis pool. They're stopped concurrently, each within the whole shutdown timeout, so that
+// a service slow to stop doesn't eat into the time of the others, e.g. files being checkpointed into the
+// time of the worker to drain its jobs.
+func Run(ctx context.Context, services ...Service) error {
+	// Kubernetes sends SIGTERM and waits for its grace period before killing the pod
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var started []Service
+	var err error
+	for _, service := range services {
+		if err = service.Start(ctx); err != nil {
+			break
+		}
+		started = append(started, service)
+	}
+	if err == nil {
+		<-ctx.Done()
+	}
+
+	log.Info("shutting down", zap.Duration("timeout", config.AppConfig.ShutdownTimeout))
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), config.AppConfig.ShutdownTimeout)
+	defer shutdownCancel()
+
+	errs := make([]error, len(started)+1)
+	errs[0] = err
+	var stopping sync.WaitGroup
+	for i, service := range started {
+		stopping.Add(1)
+		go func(i int, service Service) {
+			defer stopping.Done()
+			errs[i+1] = service.Stop(shutdownCtx)
+		}(i, service)
+	}
+	stopping.Wait()
+	if AppDependency.Redis != nil {
+		errs = append(errs, AppDependency.Redis.Close())
+	}
+	return errors.Join(errs...)
+}
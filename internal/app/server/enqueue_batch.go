@@ -0,0 +1,105 @@
+package server
+
+import (
+	"errors"
+	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/pkg/log"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// batchedJob is a job of a file waiting for its batch to be enqueued, with the line it comes from.
+type batchedJob struct {
+	line   int64
+	userID string
+	args   map[string]interface{}
+}
+
+// jobBatch gathers the jobs of a file to enqueue them together, once size of them are waiting or the
+// oldest waited for interval.
+type jobBatch struct {
+	size     int
+	interval time.Duration
+	jobs     []batchedJob
+	since    time.Time
+}
+
+func newJobBatch(size int, interval time.Duration) *jobBatch {
+	return &jobBatch{size: size, interval: interval}
+}
+
+func (b *jobBatch) add(line int64, userID string, args map[string]interface{}) {
+	if len(b.jobs) == 0 {
+		b.since = time.Now()
+	}
+	b.jobs = append(b.jobs, batchedJob{line: line, userID: userID, args: args})
+}
+
+// due tells whether the batch should be enqueued.
+func (b *jobBatch) due() bool {
+	return len(b.jobs) > 0 && (len(b.jobs) >= b.size || time.Since(b.since) >= b.interval)
+}
+
+// take empties the batch and returns its jobs.
+func (b *jobBatch) take() []batchedJob {
+	jobs := b.jobs
+	b.jobs = nil
+	return jobs
+}
+
+// flushBatch enqueues the jobs waiting in batch and adds them to counts, reporting their lines to the
+// report of the run if they could not be enqueued. Users whose job could not be enqueued are forgotten
+// by the campaign, to be sent to when it's retried.
+func (fp *FileProcessor) flushBatch(run *fileRun, counts *fileSummary, batch *jobBatch) {
+	jobs := batch.take()
+	if len(jobs) == 0 {
+		return
+	}
+
+	args := make([]map[string]interface{}, 0, len(jobs))
+	for _, job := range jobs {
+		args = append(args, job.args)
+	}
+	err := fp.enqueueJobs(run, args)
+	switch {
+	case err == nil:
+		counts.Enqueued += int64(len(jobs))
+	case errors.Is(err, errJobSpooled):
+		counts.Spooled += int64(len(jobs))
+	default:
+		log.Error("unable to queue information", zap.String("filename", run.filename), zap.Int("jobs", len(jobs)), zap.Error(err))
+		counts.Failed += int64(len(jobs))
+		userIDs := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			run.reject(job.line, job.userID, err)
+			userIDs = append(userIDs, job.userID)
+		}
+		if err := fp.dedup.Forget(run.campaign, userIDs); err != nil {
+			log.Error("unable to forget users of failed jobs", zap.String("filename", run.filename), zap.Error(err))
+		}
+	}
+}
+
+// enqueueJobs enqueues a job for each of args. The jobs of a campaign sent later are scheduled for its
+// send_at, rather than picked up by the workers only to be deferred. The jobs of encrypted files are never
+// spooled, as they'd be written to disk in plaintext.
+func (fp *FileProcessor) enqueueJobs(run *fileRun, args []map[string]interface{}) error {
+	jobName := config.AppConfig.JobName
+	delay := sendAtDelay(run.manifest, time.Now())
+	unspooled, ok := fp.enqueuer.(unspooledEnqueuer)
+	ok = ok && run.encrypted
+
+	var err error
+	switch {
+	case delay > 0 && ok:
+		_, err = unspooled.EnqueueBatchInUnspooled(jobName, delay, args)
+	case delay > 0:
+		_, err = fp.enqueuer.EnqueueBatchIn(jobName, delay, args)
+	case ok:
+		_, err = unspooled.EnqueueBatchUnspooled(jobName, args)
+	default:
+		_, err = fp.enqueuer.EnqueueBatch(jobName, args)
+	}
+	return err
+}
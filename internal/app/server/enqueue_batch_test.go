@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobBatch_Due(t *testing.T) {
+	batch := newJobBatch(2, time.Hour)
+	assert.False(t, batch.due())
+
+	batch.add(1, "1", map[string]interface{}{"userID": "1"})
+	assert.False(t, batch.due())
+	batch.add(2, "2", map[string]interface{}{"userID": "2"})
+	assert.True(t, batch.due())
+
+	jobs := batch.take()
+	assert.Len(t, jobs, 2)
+	assert.Equal(t, int64(2), jobs[1].line)
+	assert.False(t, batch.due())
+
+	// A lone job is flushed once it waited for the interval
+	batch = newJobBatch(2, 10*time.Millisecond)
+	batch.add(3, "3", map[string]interface{}{"userID": "3"})
+	assert.False(t, batch.due())
+	time.Sleep(20 * time.Millisecond)
+	assert.True(t, batch.due())
+}
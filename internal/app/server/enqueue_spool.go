@@ -0,0 +1,196 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"swilly-delivery-service/internal/pkg/log"
+	"swilly-delivery-service/internal/pkg/queue"
+	"swilly-delivery-service/internal/pkg/spool"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errJobSpooled is returned for jobs that could not be enqueued but were spooled to disk, to be
+// enqueued once redis is back.
+var errJobSpooled = errors.New("job spooled until redis is available")
+
+// errJobNotSpooled is returned for jobs that could not be enqueued and must not be spooled to disk.
+var errJobNotSpooled = errors.New("redis is unavailable and the job must not be spooled")
+
+// spoolingEnqueuer retries jobs that could not be enqueued and spools them to disk when redis stays
+// unavailable.
+type spoolingEnqueuer struct {
+	next    Enqueuer
+	spool   spool.Spool
+	retries int
+	backoff time.Duration
+	// ctx is canceled once the server stops, cutting retries short to spool the jobs right away
+	ctx    context.Context
+	cancel context.CancelFunc
+	// spooling is set from the first spooled job until the spool was replayed, meanwhile jobs are
+	// spooled right away instead of waiting on retries for each
+	spooling atomic.Bool
+}
+
+func newSpoolingEnqueuer(next Enqueuer, spool spool.Spool, retries int, backoff time.Duration) *spoolingEnqueuer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &spoolingEnqueuer{next: next, spool: spool, retries: retries, backoff: backoff, ctx: ctx, cancel: cancel}
+}
+
+// stop stops waiting between retries. Jobs that can't be enqueued are spooled after their first attempt.
+func (e *spoolingEnqueuer) stop() {
+	e.cancel()
+}
+
+func (e *spoolingEnqueuer) Enqueue(jobName string, args map[string]interface{}) (*queue.Job, error) {
+	if e.spooling.Load() {
+		return nil, e.spoolJobs(jobName, []map[string]interface{}{args}, nil)
+	}
+
+	var job *queue.Job
+	err := e.withRetries(func() (err error) {
+		job, err = e.next.Enqueue(jobName, args)
+		return err
+	})
+	if err == nil {
+		return job, nil
+	}
+
+	log.Error("unable to queue information in redis, spooling it", zap.String("jobName", jobName), zap.Error(err))
+	e.spooling.Store(true)
+	return nil, e.spoolJobs(jobName, []map[string]interface{}{args}, err)
+}
+
+// EnqueueBatch enqueues the jobs like Enqueue, retrying and spooling the batch as a whole.
+func (e *spoolingEnqueuer) EnqueueBatch(jobName string, args []map[string]interface{}) ([]*queue.Job, error) {
+	return e.enqueueBatch(jobName, args, func() ([]*queue.Job, error) {
+		return e.next.EnqueueBatch(jobName, args)
+	})
+}
+
+// EnqueueBatchIn schedules the jobs like EnqueueBatch enqueues them. Spooled jobs lose their schedule,
+// they're enqueued once replayed and the worker defers them until the send_at of their campaign.
+func (e *spoolingEnqueuer) EnqueueBatchIn(jobName string, secondsFromNow int64, args []map[string]interface{}) ([]*queue.Job, error) {
+	return e.enqueueBatch(jobName, args, func() ([]*queue.Job, error) {
+		return e.next.EnqueueBatchIn(jobName, secondsFromNow, args)
+	})
+}
+
+func (e *spoolingEnqueuer) enqueueBatch(jobName string, args []map[string]interface{}, enqueue func() ([]*queue.Job, error)) ([]*queue.Job, error) {
+	if e.spooling.Load() {
+		return nil, e.spoolJobs(jobName, args, nil)
+	}
+
+	var jobs []*queue.Job
+	err := e.withRetries(func() (err error) {
+		jobs, err = enqueue()
+		return err
+	})
+	if err == nil {
+		return jobs, nil
+	}
+
+	log.Error("unable to queue information in redis, spooling it", zap.String("jobName", jobName), zap.Int("jobs", len(args)), zap.Error(err))
+	e.spooling.Store(true)
+	return nil, e.spoolJobs(jobName, args, err)
+}
+
+// EnqueueBatchUnspooled enqueues the jobs like EnqueueBatch but fails them instead of spooling them, for
+// jobs that must not be written to disk. They fail right away while redis is known to be unavailable.
+func (e *spoolingEnqueuer) EnqueueBatchUnspooled(jobName string, args []map[string]interface{}) ([]*queue.Job, error) {
+	return e.enqueueBatchUnspooled(func() ([]*queue.Job, error) {
+		return e.next.EnqueueBatch(jobName, args)
+	})
+}
+
+// EnqueueBatchInUnspooled schedules the jobs like EnqueueBatchIn but fails them instead of spooling them.
+func (e *spoolingEnqueuer) EnqueueBatchInUnspooled(jobName string, secondsFromNow int64, args []map[string]interface{}) ([]*queue.Job, error) {
+	return e.enqueueBatchUnspooled(func() ([]*queue.Job, error) {
+		return e.next.EnqueueBatchIn(jobName, secondsFromNow, args)
+	})
+}
+
+func (e *spoolingEnqueuer) enqueueBatchUnspooled(enqueue func() ([]*queue.Job, error)) ([]*queue.Job, error) {
+	if e.spooling.Load() {
+		return nil, errJobNotSpooled
+	}
+
+	var jobs []*queue.Job
+	err := e.withRetries(func() (err error) {
+		jobs, err = enqueue()
+		return err
+	})
+	if err != nil {
+		e.spooling.Store(true)
+		return nil, fmt.Errorf("%w: %v", errJobNotSpooled, err)
+	}
+	return jobs, nil
+}
+
+// withRetries calls enqueue until it succeeds or the retries are exhausted, waiting twice as long each time.
+// It gives up on the retries left once the enqueuer is stopped.
+func (e *spoolingEnqueuer) withRetries(enqueue func() error) error {
+	err := enqueue()
+	delay := e.backoff
+	for attempt := 0; err != nil && attempt < e.retries; attempt++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-e.ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+		err = enqueue()
+	}
+	return err
+}
+
+// spoolJobs appends the jobs to the spool at once.
+func (e *spoolingEnqueuer) spoolJobs(jobName string, args []map[string]interface{}, cause error) error {
+	spooledAt := time.Now()
+	entries := make([]spool.Entry, 0, len(args))
+	for _, jobArgs := range args {
+		entries = append(entries, spool.Entry{JobName: jobName, Args: jobArgs, SpooledAt: spooledAt})
+	}
+	if err := e.spool.AppendBatch(entries); err != nil {
+		if cause == nil {
+			cause = errJobSpooled
+		}
+		return fmt.Errorf("unable to spool jobs after %v: %w", cause, err)
+	}
+	return errJobSpooled
+}
+
+// replaySpool regularly pushes the spooled jobs to redis until ctx is done.
+func (e *spoolingEnqueuer) replaySpool(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.replay()
+		}
+	}
+}
+
+func (e *spoolingEnqueuer) replay() {
+	replayed, err := e.spool.Replay(func(entry spool.Entry) error {
+		_, err := e.next.Enqueue(entry.JobName, entry.Args)
+		return err
+	})
+	if replayed > 0 {
+		log.Info("Enqueued spooled jobs", zap.Int("count", replayed))
+	}
+	if err != nil {
+		log.Error("unable to enqueue spooled jobs", zap.Error(err))
+		return
+	}
+	e.spooling.Store(false)
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"errors"
+	"path/filepath"
+	"swilly-delivery-service/internal/pkg/queue"
+	"swilly-delivery-service/internal/pkg/spool"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpoolingEnqueuer(t *testing.T) {
+	controller := gomock.NewController(t)
+	next := NewMockEnqueuer(controller)
+	jobSpool, err := spool.NewFileSpool(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	require.NoError(t, err)
+	enqueuer := newSpoolingEnqueuer(next, jobSpool, 2, 0)
+
+	// Spooled once the retries are exhausted, and right away after that
+	next.EXPECT().Enqueue("send_message", map[string]interface{}{"userID": "1"}).Return(nil, errors.New("connection refused")).Times(3)
+	_, err = enqueuer.Enqueue("send_message", map[string]interface{}{"userID": "1"})
+	assert.True(t, errors.Is(err, errJobSpooled))
+	_, err = enqueuer.Enqueue("send_message", map[string]interface{}{"userID": "2"})
+	assert.True(t, errors.Is(err, errJobSpooled))
+
+	// Replayed in order once redis is back
+	gomock.InOrder(
+		next.EXPECT().Enqueue("send_message", map[string]interface{}{"userID": "1"}).Return(&queue.Job{}, nil),
+		next.EXPECT().Enqueue("send_message", map[string]interface{}{"userID": "2"}).Return(&queue.Job{}, nil),
+	)
+	enqueuer.replay()
+
+	next.EXPECT().Enqueue("send_message", map[string]interface{}{"userID": "3"}).Return(&queue.Job{}, nil)
+	_, err = enqueuer.Enqueue("send_message", map[string]interface{}{"userID": "3"})
+	assert.NoError(t, err)
+}
+
+func TestSpoolingEnqueuer_EnqueueBatch(t *testing.T) {
+	controller := gomock.NewController(t)
+	next := NewMockEnqueuer(controller)
+	jobSpool, err := spool.NewFileSpool(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	require.NoError(t, err)
+	enqueuer := newSpoolingEnqueuer(next, jobSpool, 1, 0)
+	batch := []map[string]interface{}{{"userID": "1"}, {"userID": "2"}}
+
+	// The batch is spooled as a whole, then replayed job by job
+	next.EXPECT().EnqueueBatch("send_message", batch).Return(nil, errors.New("connection refused")).Times(2)
+	_, err = enqueuer.EnqueueBatch("send_message", batch)
+	assert.True(t, errors.Is(err, errJobSpooled))
+
+	gomock.InOrder(
+		next.EXPECT().Enqueue("send_message", map[string]interface{}{"userID": "1"}).Return(&queue.Job{}, nil),
+		next.EXPECT().Enqueue("send_message", map[string]interface{}{"userID": "2"}).Return(&queue.Job{}, nil),
+	)
+	enqueuer.replay()
+
+	next.EXPECT().EnqueueBatch("send_message", batch).Return([]*queue.Job{{}, {}}, nil)
+	jobs, err := enqueuer.EnqueueBatch("send_message", batch)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+}
+
+func TestSpoolingEnqueuer_EnqueueBatchIn(t *testing.T) {
+	controller := gomock.NewController(t)
+	next := NewMockEnqueuer(controller)
+	jobSpool, err := spool.NewFileSpool(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	require.NoError(t, err)
+	enqueuer := newSpoolingEnqueuer(next, jobSpool, 1, 0)
+	batch := []map[string]interface{}{{"userID": "1", "send_at": "2026-10-20T09:00:00Z"}}
+
+	next.EXPECT().EnqueueBatchIn("send_message", int64(60), batch).Return([]*queue.Job{{}}, nil)
+	jobs, err := enqueuer.EnqueueBatchIn("send_message", 60, batch)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 1)
+
+	// Spooled like any batch, the worker defers the job once replayed
+	next.EXPECT().EnqueueBatchIn("send_message", int64(60), batch).Return(nil, errors.New("connection refused")).Times(2)
+	_, err = enqueuer.EnqueueBatchIn("send_message", 60, batch)
+	assert.True(t, errors.Is(err, errJobSpooled))
+
+	next.EXPECT().Enqueue("send_message", batch[0]).Return(&queue.Job{}, nil)
+	enqueuer.replay()
+}
+
+func TestSpoolingEnqueuer_EnqueueBatchUnspooled(t *testing.T) {
+	controller := gomock.NewController(t)
+	next := NewMockEnqueuer(controller)
+	spoolPath := filepath.Join(t.TempDir(), "jobs.jsonl")
+	jobSpool, err := spool.NewFileSpool(spoolPath)
+	require.NoError(t, err)
+	enqueuer := newSpoolingEnqueuer(next, jobSpool, 1, 0)
+	batch := []map[string]interface{}{{"userID": "1"}, {"userID": "2"}}
+
+	// Failed once the retries are exhausted, and right away after that
+	next.EXPECT().EnqueueBatch("send_message", batch).Return(nil, errors.New("connection refused")).Times(2)
+	_, err = enqueuer.EnqueueBatchUnspooled("send_message", batch)
+	assert.True(t, errors.Is(err, errJobNotSpooled))
+	_, err = enqueuer.EnqueueBatchUnspooled("send_message", batch)
+	assert.True(t, errors.Is(err, errJobNotSpooled))
+
+	replayed, err := jobSpool.Replay(func(spool.Entry) error { return nil })
+	require.NoError(t, err)
+	assert.Zero(t, replayed)
+
+	// Enqueued again once redis is back
+	enqueuer.replay()
+	next.EXPECT().EnqueueBatch("send_message", batch).Return([]*queue.Job{{}, {}}, nil)
+	jobs, err := enqueuer.EnqueueBatchUnspooled("send_message", batch)
+	require.NoError(t, err)
+	assert.Len(t, jobs, 2)
+}
+
+func TestSpoolingEnqueuer_StopCutsRetriesShort(t *testing.T) {
+	controller := gomock.NewController(t)
+	next := NewMockEnqueuer(controller)
+	jobSpool, err := spool.NewFileSpool(filepath.Join(t.TempDir(), "jobs.jsonl"))
+	require.NoError(t, err)
+	enqueuer := newSpoolingEnqueuer(next, jobSpool, 3, time.Hour)
+	batch := []map[string]interface{}{{"userID": "1"}, {"userID": "2"}}
+
+	next.EXPECT().EnqueueBatch("send_message", batch).Return(nil, errors.New("connection refused"))
+	time.AfterFunc(10*time.Millisecond, enqueuer.stop)
+	_, err = enqueuer.EnqueueBatch("send_message", batch)
+	assert.True(t, errors.Is(err, errJobSpooled))
+
+	replayed, err := jobSpool.Replay(func(spool.Entry) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+}
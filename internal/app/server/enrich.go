@@ -0,0 +1,85 @@
+package server
+
+import (
+	"errors"
+	"swilly-delivery-service/internal/pkg/log"
+	"swilly-delivery-service/internal/pkg/profile"
+
+	"go.uber.org/zap"
+)
+
+// parsedUser is a user ID read from a file, along with the profile of the user once looked up.
+type parsedUser struct {
+	line   int64
+	userID string
+	// profile is nil when enrichment is disabled, or when the user-profile API doesn't know of the user
+	profile *profile.Profile
+	// lookupErr is set when the profile of the user could not be looked up
+	lookupErr error
+	// skipErr is set once the user is screened, when their job is not to be enqueued
+	skipErr error
+}
+
+// enrichUsers looks up the profiles of users together and screens them together, then adds the jobs of
+// users to batch and counts the outcome of each.
+func (fp *FileProcessor) enrichUsers(run *fileRun, counts *fileSummary, batch *jobBatch, users []*parsedUser) {
+	fp.lookupProfiles(users)
+	fp.screenUsers(run, users)
+
+	for _, user := range users {
+		err := fp.processUserID(run, batch, user)
+		switch {
+		case err == nil:
+			// Counted once its batch is enqueued
+		case errors.Is(err, errUserSuppressed):
+			counts.Suppressed++
+		case errors.Is(err, errDuplicateUser):
+			counts.Duplicates++
+		case errors.Is(err, errUserInactive):
+			counts.Inactive++
+		case errors.Is(err, errInvalidUserID):
+			// Already counted and reported when inspecting the file
+		default:
+			counts.Failed++
+			run.reject(user.line, user.userID, err)
+			log.Error("Error processing userID", run.userIDField(user.userID), zap.String("filename", run.filename), zap.Error(err))
+		}
+		if batch.due() {
+			fp.flushBatch(run, counts, batch)
+		}
+	}
+}
+
+// lookupProfiles sets the profile of the valid users, unless enrichment is disabled.
+func (fp *FileProcessor) lookupProfiles(users []*parsedUser) {
+	if fp.profiles == nil {
+		return
+	}
+
+	userIDs := make([]string, 0, len(users))
+	for _, user := range users {
+		if fp.validateUserID(user.userID) == nil {
+			userIDs = append(userIDs, user.userID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+
+	profiles, err := fp.profiles.Lookup(userIDs)
+	for _, user := range users {
+		if err != nil {
+			user.lookupErr = err
+			continue
+		}
+		if found, ok := profiles[user.userID]; ok {
+			user.profile = &found
+		}
+	}
+}
+
+// isInactive tells whether the user is to be treated as inactive, which users the user-profile API
+// doesn't know of are too.
+func (fp *FileProcessor) isInactive(user *parsedUser) bool {
+	return fp.profiles != nil && (user.profile == nil || !user.profile.Active)
+}
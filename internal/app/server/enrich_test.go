@@ -0,0 +1,113 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/pkg/profile"
+	"swilly-delivery-service/internal/pkg/queue"
+
+	"github.com/golang/mock/gomock"
+)
+
+// processEnrichedFile processes a file of the user IDs 1 to 4, looked up 3 at a time.
+func (f *FileProcessSuite) processEnrichedFile(profiles profile.Client, inactivePolicy string) {
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("1\n2\n3\n4\ninvalid\n"), 0644))
+
+	f.dependency.Profiles = profiles
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.thresholds.quarantineInvalidPercent = 50
+	fp.thresholds.failedEnqueuePercent = 100
+	fp.lookupBatchSize = 3
+	fp.inactivePolicy = inactivePolicy
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+}
+
+// expectEnrichedFile expects userIDs of the enriched file to reach the suppression and dedup checks.
+func (f *FileProcessSuite) expectEnrichedFile(userIDs int) {
+	f.grantLeases()
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.checksums.EXPECT().Release(gomock.Any()).Return(nil).AnyTimes()
+	f.expectScreened("swilly_test_file", userIDs)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+}
+
+// mockProfiles knows of the users 1 to 3, 2 being inactive.
+func (f *FileProcessSuite) mockProfiles() *profile.MockClient {
+	profiles := profile.NewMockClient(gomock.NewController(f.T()))
+	profiles.EXPECT().Lookup([]string{"1", "2", "3"}).Return(map[string]profile.Profile{
+		"1": {UserID: "1", Locale: "en_IN", Timezone: "Asia/Kolkata", Channels: []string{"push"}, Active: true},
+		"2": {UserID: "2", Locale: "hi_IN", Timezone: "Asia/Kolkata", Channels: []string{"sms"}, Active: false},
+		"3": {UserID: "3", Locale: "en_US", Timezone: "America/New_York", Channels: []string{"email", "push"}, Active: true},
+	}, nil)
+	profiles.EXPECT().Lookup([]string{"4"}).Return(map[string]profile.Profile{}, nil)
+	return profiles
+}
+
+func (f *FileProcessSuite) TestFileProcessor_EnrichDropsInactiveUsers() {
+	f.expectEnrichedFile(2)
+	var userIDs []string
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ string, args []map[string]interface{}) ([]*queue.Job, error) {
+			for _, jobArgs := range args {
+				userIDs = append(userIDs, jobArgs["userID"].(string))
+				f.Equal(true, jobArgs["active"])
+			}
+			f.Equal(map[string]interface{}{
+				"userID": "1", "message": "message", "filename": "swilly_test_file", "campaign": "swilly_test_file",
+				"active": true, "locale": "en_IN", "timezone": "Asia/Kolkata", "channels": []string{"push"},
+			}, args[0])
+			return nil, nil
+		})
+
+	f.processEnrichedFile(f.mockProfiles(), config.InactivePolicyDrop)
+
+	f.Equal([]string{"1", "3"}, userIDs)
+	summary := f.readSummary(processedFolder, "swilly_test_file")
+	f.Equal(int64(2), summary.Enqueued)
+	f.Equal(int64(2), summary.Inactive)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_EnrichFlagsInactiveUsers() {
+	f.expectEnrichedFile(4)
+	active := map[string]interface{}{}
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ string, args []map[string]interface{}) ([]*queue.Job, error) {
+			for _, jobArgs := range args {
+				active[jobArgs["userID"].(string)] = jobArgs["active"]
+			}
+			return nil, nil
+		})
+
+	f.processEnrichedFile(f.mockProfiles(), config.InactivePolicyFlag)
+
+	f.Equal(map[string]interface{}{"1": true, "2": false, "3": true, "4": false}, active)
+	summary := f.readSummary(processedFolder, "swilly_test_file")
+	f.Equal(int64(4), summary.Enqueued)
+	f.Zero(summary.Inactive)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_EnrichLookupFailure() {
+	f.expectEnrichedFile(1)
+	profiles := profile.NewMockClient(gomock.NewController(f.T()))
+	profiles.EXPECT().Lookup([]string{"1", "2", "3"}).Return(nil, errors.New("connection refused"))
+	profiles.EXPECT().Lookup([]string{"4"}).Return(map[string]profile.Profile{
+		"4": {UserID: "4", Active: true},
+	}, nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	f.processEnrichedFile(profiles, config.InactivePolicyDrop)
+
+	summary := f.readSummary(processedFolder, "swilly_test_file")
+	f.Equal(int64(1), summary.Enqueued)
+	f.Equal(int64(3), summary.Failed)
+	report, err := os.ReadFile(filepath.Join(f.tmpDir, processedFolder, "swilly_test_file.report.csv"))
+	f.NoError(err)
+	f.Contains(string(report), "1,1,unable to look up user profile: connection refused\n")
+}
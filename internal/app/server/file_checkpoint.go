@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"swilly-delivery-service/internal/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// fileCheckpoint is where a run interrupted by a shutdown stopped, so that it can be resumed
+// after a restart without enqueueing the same lines twice.
+type fileCheckpoint struct {
+	RunID    string `json:"run_id"`
+	Checksum string `json:"checksum"`
+	Claimed  bool   `json:"claimed"`
+	// Progress is the number of lines handled over all chunks
+	Progress int64        `json:"progress"`
+	Chunks   []*fileChunk `json:"chunks"`
+	Summary  fileSummary  `json:"summary"`
+}
+
+const checkpointSuffix = ".checkpoint.json"
+
+func checkpointPath(path string) string {
+	return path + checkpointSuffix
+}
+
+// checkpointRun closes the report of an interrupted run and records its progress next to the file,
+// which stays in the processing folder.
+func (fp *FileProcessor) checkpointRun(run *fileRun, reason string) {
+	log.Info("Interrupted file processing",
+		zap.String("filename", run.filename),
+		zap.String("reason", reason),
+		zap.Int64("progress", run.progress()),
+	)
+
+	if err := run.closeReport(); err != nil {
+		log.Error("unable to write report", zap.String("filename", run.filename), zap.Error(err))
+	}
+
+	data, err := json.MarshalIndent(fileCheckpoint{
+		RunID:    run.id,
+		Checksum: run.checksum,
+		Claimed:  run.claimed,
+		Progress: run.progress(),
+		Chunks:   run.chunks,
+		Summary:  run.summary,
+	}, "", "  ")
+	if err == nil {
+		err = os.WriteFile(checkpointPath(run.path), data, 0644)
+	}
+	if err != nil {
+		log.Error("unable to write checkpoint, file will be processed again from the start", zap.String("filename", run.filename), zap.Error(err))
+	}
+}
+
+// resumeRun restores a run from the checkpoint left next to the file, if any.
+func resumeRun(run *fileRun) (bool, error) {
+	data, err := os.ReadFile(checkpointPath(run.path))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var checkpoint fileCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return false, err
+	}
+	run.id = checkpoint.RunID
+	run.checksum = checkpoint.Checksum
+	run.claimed = checkpoint.Claimed
+	run.chunks = checkpoint.Chunks
+	run.summary = checkpoint.Summary
+	run.resumed = true
+	return true, nil
+}
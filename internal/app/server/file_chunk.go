@@ -0,0 +1,142 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"swilly-delivery-service/internal/pkg/log"
+	"swilly-delivery-service/internal/pkg/recipient"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// fileChunk is a byte range of a file, starting and ending on a line boundary, whose lines are processed
+// apart from those of the other chunks.
+type fileChunk struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	// FirstLine is the number of the first line of the chunk in the file
+	FirstLine int64 `json:"first_line"`
+	Lines     int64 `json:"lines"`
+	// Progress is the number of lines of the chunk already handled
+	Progress int64 `json:"progress"`
+
+	// counts are the outcomes of the lines handled by the current run, added to the summary of the run
+	// once all chunks are done with
+	counts fileSummary
+}
+
+// chunker splits a file into chunks of about size bytes while it's scanned line by line.
+type chunker struct {
+	size   int64
+	offset int64
+	chunks []*fileChunk
+}
+
+// scanLines is a bufio.SplitFunc returning lines like bufio.ScanLines, and adding them to the chunk being
+// filled up. A new chunk is started once the current one holds size bytes, 0 to never start one.
+func (c *chunker) scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if token == nil {
+		return advance, token, err
+	}
+
+	if len(c.chunks) == 0 {
+		c.chunks = append(c.chunks, &fileChunk{FirstLine: 1})
+	} else if last := c.chunks[len(c.chunks)-1]; c.size > 0 && last.End-last.Start >= c.size {
+		c.chunks = append(c.chunks, &fileChunk{Start: last.End, End: last.End, FirstLine: last.FirstLine + last.Lines})
+	}
+	chunk := c.chunks[len(c.chunks)-1]
+	c.offset += int64(advance)
+	chunk.End = c.offset
+	chunk.Lines++
+	return advance, token, err
+}
+
+// ingestChunks enqueues the user IDs of the chunks of the run not handled yet, fp.chunkWorkers chunks at
+// once. It returns the folder the file belongs in and the reason why if a chunk was interrupted or
+// failed, or "" once all chunks are done with.
+func (fp *FileProcessor) ingestChunks(ctx context.Context, run *fileRun, file io.ReaderAt) (string, string) {
+	// A failed chunk fails the file, there is no point going on with the others
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct{ folder, reason string }
+	outcomes := make([]outcome, len(run.chunks))
+	slots := make(chan struct{}, fp.chunkWorkers)
+	var wg sync.WaitGroup
+	for i, chunk := range run.chunks {
+		if chunk.Progress >= chunk.Lines {
+			continue
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int, chunk *fileChunk) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			folder, reason := fp.ingestChunk(ctx, run, file, chunk)
+			if folder == failedFolder {
+				cancel()
+			}
+			outcomes[i] = outcome{folder, reason}
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	// The first failed chunk decides where the file goes, over the first interrupted one
+	folder, reason := "", ""
+	for i, chunk := range run.chunks {
+		run.summary.add(chunk.counts)
+		chunk.counts = fileSummary{}
+		if outcomes[i].folder != "" && (folder == "" || outcomes[i].folder == failedFolder && folder != failedFolder) {
+			folder, reason = outcomes[i].folder, outcomes[i].reason
+		}
+	}
+	return folder, reason
+}
+
+// ingestChunk enqueues the user IDs of a chunk of the file, enriched and screened in batches of fp.lookupBatchSize,
+// reporting rejected lines to the report of the run. It returns the folder the file belongs in and the reason why if the chunk could not be finished.
+func (fp *FileProcessor) ingestChunk(ctx context.Context, run *fileRun, file io.ReaderAt, chunk *fileChunk) (string, string) {
+	counts := &chunk.counts
+	var handled int64
+	line := chunk.FirstLine - 1
+	batch := newJobBatch(fp.batchSize, fp.flushInterval)
+	users := make([]*parsedUser, 0, fp.lookupBatchSize)
+	scanner := bufio.NewScanner(io.NewSectionReader(file, chunk.Start, chunk.End-chunk.Start))
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			// Lines up to the checkpointed progress must have their job enqueued
+			fp.enrichUsers(run, counts, batch, users)
+			fp.flushBatch(run, counts, batch)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return processingFolder, fmt.Sprintf("processing timed out at line %d", line)
+			}
+			return processingFolder, fmt.Sprintf("processing interrupted at line %d", line)
+		}
+
+		line++
+		handled++
+		if handled <= chunk.Progress {
+			continue
+		}
+		chunk.Progress = handled
+		users = append(users, &parsedUser{line: line, userID: recipient.Normalize(scanner.Text())})
+		if len(users) >= fp.lookupBatchSize {
+			fp.enrichUsers(run, counts, batch, users)
+			users = users[:0]
+		}
+	}
+	fp.enrichUsers(run, counts, batch, users)
+	fp.flushBatch(run, counts, batch)
+
+	if err := scanner.Err(); err != nil {
+		log.Error("Error scanning file", zap.String("filename", run.filename), zap.Error(err))
+		return failedFolder, fmt.Sprintf("unable to read file at line %d: %v", line, err)
+	}
+	return "", ""
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"swilly-delivery-service/internal/pkg/queue"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunker_SplitsOnLineBoundaries(t *testing.T) {
+	data := "1\n22\n333\n4444\r\n5"
+	chunks := &chunker{size: 4}
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	scanner.Split(chunks.scanLines)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"1", "22", "333", "4444", "5"}, lines)
+
+	assert.Equal(t, []*fileChunk{
+		{Start: 0, End: 5, FirstLine: 1, Lines: 2},
+		{Start: 5, End: 9, FirstLine: 3, Lines: 1},
+		{Start: 9, End: 15, FirstLine: 4, Lines: 1},
+		{Start: 15, End: 16, FirstLine: 5, Lines: 1},
+	}, chunks.chunks)
+	for _, chunk := range chunks.chunks {
+		section, err := io.ReadAll(io.NewSectionReader(strings.NewReader(data), chunk.Start, chunk.End-chunk.Start))
+		require.NoError(t, err)
+		assert.Equal(t, int(chunk.Lines), strings.Count(strings.TrimSuffix(string(section), "\n"), "\n")+1)
+	}
+
+	// Files are not split without a size
+	chunks = &chunker{}
+	scanner = bufio.NewScanner(strings.NewReader(data))
+	scanner.Split(chunks.scanLines)
+	for scanner.Scan() {
+	}
+	assert.Equal(t, []*fileChunk{{Start: 0, End: 16, FirstLine: 1, Lines: 5}}, chunks.chunks)
+}
+
+// recordUserIDs has the mock enqueuer record the user IDs of the batches it's given.
+func (f *FileProcessSuite) recordUserIDs(userIDs *[]string, batch func()) {
+	var mutex sync.Mutex
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ string, args []map[string]interface{}) ([]*queue.Job, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			for _, jobArgs := range args {
+				*userIDs = append(*userIDs, jobArgs["userID"].(string))
+			}
+			if batch != nil {
+				batch()
+			}
+			return nil, nil
+		}).AnyTimes()
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ProcessChunkedFile() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("101\n102\n103\ninvalid\n105\n106\n107\n108\n109\n"), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.expectScreened("swilly_test_file", 8)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	var userIDs []string
+	f.recordUserIDs(&userIDs, nil)
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.thresholds.quarantineInvalidPercent = 50
+	fp.chunkSize = 8
+	fp.chunkWorkers = 3
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	f.ElementsMatch([]string{"101", "102", "103", "105", "106", "107", "108", "109"}, userIDs)
+	summary := f.readSummary(processedFolder, "swilly_test_file")
+	f.Equal(int64(9), summary.Lines)
+	f.Equal(int64(8), summary.Enqueued)
+	f.Equal(int64(1), summary.Invalid)
+	report, err := os.ReadFile(filepath.Join(f.tmpDir, processedFolder, "swilly_test_file.report.csv"))
+	f.NoError(err)
+	f.Equal("line,value,reason\n4,invalid,invalid user ID\n", string(report))
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ResumeInterruptedChunks() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("101\n102\n103\n104\n105\n106\n"), 0644))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Claimed once only, the resumed run carries on with the chunks left
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.expectScreened(gomock.Any(), 6)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	var userIDs []string
+	f.recordUserIDs(&userIDs, func() {
+		if len(userIDs) == 3 {
+			cancel()
+		}
+	})
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.batchSize = 1
+	fp.lookupBatchSize = 1
+	fp.chunkSize = 8
+	fp.chunkWorkers = 1
+	fp.wg.Add(1)
+	fp.processFile(ctx, filename)
+
+	processingFilename := filepath.Join(f.tmpDir, processingFolder, "swilly_test_file")
+	data, err := os.ReadFile(checkpointPath(processingFilename))
+	f.NoError(err)
+	var checkpoint fileCheckpoint
+	f.NoError(json.Unmarshal(data, &checkpoint))
+	f.Equal(int64(3), checkpoint.Progress)
+	f.Len(checkpoint.Chunks, 3)
+	f.Equal([]int64{2, 1, 0}, []int64{checkpoint.Chunks[0].Progress, checkpoint.Chunks[1].Progress, checkpoint.Chunks[2].Progress})
+
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), processingFilename)
+
+	f.Equal([]string{"101", "102", "103", "104", "105", "106"}, userIDs)
+	summary := f.readSummary(processedFolder, "swilly_test_file")
+	f.Equal(int64(6), summary.Lines)
+	f.Equal(int64(6), summary.Enqueued)
+}
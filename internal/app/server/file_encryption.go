@@ -0,0 +1,42 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"swilly-delivery-service/internal/pkg/encryption"
+
+	"go.uber.org/zap"
+)
+
+var errNoDecryptionKey = errors.New("no age identities are configured")
+
+// openContent returns the content of the run read from file, and its size. The content of an encrypted
+// file is decrypted as it's read, it's never written anywhere in plaintext.
+func (fp *FileProcessor) openContent(run *fileRun, file *os.File) (io.ReaderAt, int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if !run.encrypted {
+		return file, info.Size(), nil
+	}
+	if fp.decrypter == nil {
+		return nil, 0, errNoDecryptionKey
+	}
+	return fp.decrypter.Decrypt(file, info.Size())
+}
+
+// trimEncryptionSuffix returns the name of the file an encrypted file encrypts.
+func trimEncryptionSuffix(name string) string {
+	return strings.TrimSuffix(name, encryption.Suffix)
+}
+
+// userIDField logs userID, unless it was read from an encrypted file whose content mustn't leak to the logs.
+func (r *fileRun) userIDField(userID string) zap.Field {
+	if r.encrypted {
+		return zap.Skip()
+	}
+	return zap.String("userID", userID)
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"swilly-delivery-service/internal/pkg/encryption"
+	"swilly-delivery-service/internal/pkg/spool"
+
+	"filippo.io/age"
+	"github.com/golang/mock/gomock"
+)
+
+// useDecrypter has files encrypted to the returned identity decrypted.
+func (f *FileProcessSuite) useDecrypter() *age.X25519Identity {
+	identity, err := age.GenerateX25519Identity()
+	f.NoError(err)
+	f.dependency.Decrypter, err = encryption.ParseDecrypter(strings.NewReader(identity.String()))
+	f.NoError(err)
+	return identity
+}
+
+func (f *FileProcessSuite) encrypt(recipient age.Recipient, plaintext string) []byte {
+	var encrypted bytes.Buffer
+	w, err := age.Encrypt(&encrypted, recipient)
+	f.NoError(err)
+	_, err = io.WriteString(w, plaintext)
+	f.NoError(err)
+	f.NoError(w.Close())
+	return encrypted.Bytes()
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ProcessEncryptedFile() {
+	f.grantLeases()
+	identity := f.useDecrypter()
+	plaintext := "918273641\n918273642\n918273643\ninvalid\n918273645\n918273646\n"
+	encrypted := f.encrypt(identity.Recipient(), plaintext)
+	filename := filepath.Join(f.tmpDir, "swilly_diwali"+encryption.Suffix)
+	f.NoError(os.WriteFile(filename, encrypted, 0644))
+
+	// The same content encrypted again is a duplicate, the checksum being that of the plaintext
+	checksum := sha256.Sum256([]byte(plaintext))
+	f.checksums.EXPECT().Claim(hex.EncodeToString(checksum[:]), "swilly_diwali.age").Return(true, "", nil)
+	f.expectScreened("swilly_diwali", 5)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	var userIDs []string
+	f.recordUserIDs(&userIDs, nil)
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.thresholds.quarantineInvalidPercent = 50
+	fp.chunkSize = 20
+	fp.chunkWorkers = 3
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	f.ElementsMatch([]string{"918273641", "918273642", "918273643", "918273645", "918273646"}, userIDs)
+	summary := f.readSummary(processedFolder, "swilly_diwali.age")
+	f.True(summary.Encrypted)
+	f.Equal(int64(6), summary.Lines)
+	f.Equal(int64(5), summary.Enqueued)
+	report, err := os.ReadFile(filepath.Join(f.tmpDir, processedFolder, "swilly_diwali.age.report.csv"))
+	f.NoError(err)
+	f.Equal("line,value,reason\n4,,invalid user ID\n", string(report))
+
+	// The encrypted original is archived, and nothing was written in plaintext
+	archived, err := os.ReadFile(filepath.Join(f.tmpDir, processedFolder, "swilly_diwali.age"))
+	f.NoError(err)
+	f.Equal(encrypted, archived)
+	f.NoError(filepath.Walk(f.tmpDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		f.NoError(err)
+		f.NotContains(string(data), "918273642", path)
+		return nil
+	}))
+}
+
+func (f *FileProcessSuite) TestFileProcessor_FailEncryptedFileInsteadOfSpooling() {
+	f.grantLeases()
+	identity := f.useDecrypter()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali"+encryption.Suffix)
+	f.NoError(os.WriteFile(filename, f.encrypt(identity.Recipient(), "918273641\n918273642\n"), 0644))
+	jobSpool, err := spool.NewFileSpool(filepath.Join(f.tmpDir, "spool.jsonl"))
+	f.NoError(err)
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_diwali.age").Return(true, "", nil)
+	f.checksums.EXPECT().Release(gomock.Any()).Return(nil)
+	f.expectScreened("swilly_diwali", 2)
+	f.dedup.EXPECT().Forget("swilly_diwali", []string{"918273641", "918273642"}).Return(nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused"))
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, newSpoolingEnqueuer(f.enqueuer, jobSpool, 0, 0), f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	summary := f.readSummary(failedFolder, "swilly_diwali.age")
+	f.Equal(int64(2), summary.Failed)
+	f.Zero(summary.Spooled)
+	replayed, err := jobSpool.Replay(func(spool.Entry) error { return nil })
+	f.NoError(err)
+	f.Zero(replayed)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_FailUndecryptableFiles() {
+	identity := f.useDecrypter()
+	decrypter := f.dependency.Decrypter
+	other, err := age.GenerateX25519Identity()
+	f.NoError(err)
+
+	cases := map[string]struct {
+		content func() []byte
+		prepare func()
+		reason  string
+	}{
+		"unknown key": {
+			content: func() []byte { return f.encrypt(other.Recipient(), "1\n") },
+			prepare: func() {},
+			reason:  "unable to decrypt file: identity did not match any of the recipients",
+		},
+		"no identities": {
+			content: func() []byte { return f.encrypt(identity.Recipient(), "1\n") },
+			prepare: func() { f.dependency.Decrypter = nil },
+			reason:  "unable to decrypt file: no age identities are configured",
+		},
+		"not encrypted": {
+			content: func() []byte { return []byte("1\n") },
+			prepare: func() {},
+			reason:  "unable to decrypt file: failed to read header",
+		},
+	}
+	for name, c := range cases {
+		f.Run(name, func() {
+			f.grantLeases()
+			f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+			f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			f.dependency.Decrypter = decrypter
+			c.prepare()
+			filename := filepath.Join(f.tmpDir, "swilly_"+strings.ReplaceAll(name, " ", "_")+encryption.Suffix)
+			f.NoError(os.WriteFile(filename, c.content(), 0644))
+
+			fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+			f.NoError(err)
+			fp.wg.Add(1)
+			fp.processFile(context.Background(), filename)
+
+			summary := f.readSummary(failedFolder, filepath.Base(filename))
+			f.Contains(summary.Reason, c.reason)
+			f.Zero(summary.Lines)
+		})
+	}
+}
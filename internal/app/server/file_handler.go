@@ -0,0 +1,91 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"swilly-delivery-service/internal/pkg/manifest"
+	"swilly-delivery-service/internal/pkg/stats"
+)
+
+type fileHandler struct {
+	stats stats.Store
+	// directory is where files are dropped, their manifests being found there or in a lifecycle folder
+	directory string
+}
+
+// handle routes GET /files/{name}/stats and GET /files/{name}/manifest.
+func (h *fileHandler) handle(w http.ResponseWriter, r *http.Request) {
+	name, resource, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/files/"), "/")
+	if r.Method != http.MethodGet || name == "" || name == "." || name == ".." {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	switch resource {
+	case "stats":
+		h.getStats(w, name)
+	case "manifest":
+		h.getManifest(w, name)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// getStats godoc
+//
+//	@Summary Processing counters of a file
+//	@Description Returns the ingestion and delivery counters recorded for a processed file.
+//	@Tags files
+//	@Produce json
+//	@Param name path string true "File name"
+//	@Success 200 {object} map[string]int64
+//	@Failure 404 {object} errorResponse
+//	@Failure 500 {object} errorResponse
+//	@Router /files/{name}/stats [get]
+func (h *fileHandler) getStats(w http.ResponseWriter, name string) {
+	counts, err := h.stats.Get(stats.FileScope(name))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if len(counts) == 0 {
+		writeError(w, http.StatusNotFound, errors.New("no stats recorded for file"))
+		return
+	}
+	writeJSON(w, http.StatusOK, counts)
+}
+
+// getManifest godoc
+//
+//	@Summary Manifest of a file
+//	@Description Returns the manifest describing the campaign of a file, whether it's waiting, being processed or done with.
+//	@Tags files
+//	@Produce json
+//	@Param name path string true "File name"
+//	@Success 200 {object} manifest.Manifest
+//	@Failure 404 {object} errorResponse
+//	@Failure 500 {object} errorResponse
+//	@Router /files/{name}/manifest [get]
+func (h *fileHandler) getManifest(w http.ResponseWriter, name string) {
+	for _, folder := range append([]string{""}, lifecycleFolders...) {
+		path, found := findManifest(filepath.Join(h.directory, folder, name))
+		if !found {
+			continue
+		}
+		m, err := manifest.Read(path)
+		if errors.Is(err, os.ErrNotExist) {
+			// Moved to another folder meanwhile
+			continue
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, m)
+		return
+	}
+	writeError(w, http.StatusNotFound, errors.New("no manifest found for file"))
+}
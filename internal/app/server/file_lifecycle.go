@@ -0,0 +1,94 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"swilly-delivery-service/config"
+)
+
+// A file dropped in the directory is claimed by moving it to the processing folder, and lands in
+// one of the other folders once done with.
+const (
+	processingFolder = "processing"
+	processedFolder  = "processed"
+	failedFolder     = "failed"
+	quarantineFolder = "quarantine"
+	duplicatesFolder = "duplicates"
+)
+
+var lifecycleFolders = []string{processingFolder, processedFolder, failedFolder, quarantineFolder, duplicatesFolder}
+
+// fileThresholds decide whether a file is processed, failed or quarantined.
+type fileThresholds struct {
+	quarantineInvalidPercent float64
+	failedEnqueuePercent     float64
+	rowCountTolerancePercent float64
+}
+
+func newFileThresholds() fileThresholds {
+	return fileThresholds{
+		quarantineInvalidPercent: config.AppConfig.FileLifecycleConfig.QuarantineInvalidPercent,
+		failedEnqueuePercent:     config.AppConfig.FileLifecycleConfig.FailedEnqueuePercent,
+		rowCountTolerancePercent: config.AppConfig.ManifestConfig.RowCountTolerancePercent,
+	}
+}
+
+func createLifecycleFolders(directory string) error {
+	for _, folder := range lifecycleFolders {
+		if err := os.MkdirAll(filepath.Join(directory, folder), 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimFile moves a newly dropped file and its sidecars to the processing folder and returns its new path.
+// Files already in the processing folder, left over by a previous run, are processed in place.
+func (fp *FileProcessor) claimFile(filename string) (string, error) {
+	processingDir := filepath.Join(fp.directory, processingFolder)
+	if filepath.Dir(filename) == processingDir {
+		return filename, nil
+	}
+
+	path := filepath.Join(processingDir, filepath.Base(filename))
+	if err := os.Rename(filename, path); err != nil {
+		return "", err
+	}
+	if err := moveSidecars(filename, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// settleFile moves a claimed file, its sidecars, report and segment members to folder, next to a file stating the reason if any.
+func (fp *FileProcessor) settleFile(path, folder, reason string) error {
+	name := filepath.Base(path)
+	destination := filepath.Join(fp.directory, folder)
+
+	if err := os.Rename(path, filepath.Join(destination, name)); err != nil {
+		return err
+	}
+	if err := os.Rename(reportPath(path), reportPath(filepath.Join(destination, name))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(membersPath(path), membersPath(filepath.Join(destination, name))); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := moveSidecars(path, filepath.Join(destination, name)); err != nil {
+		return err
+	}
+	if err := os.Remove(checkpointPath(path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if reason == "" {
+		return nil
+	}
+	return os.WriteFile(filepath.Join(destination, name+".reason"), []byte(reason+"\n"), 0644)
+}
+
+func percentOf(count, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(count) * 100 / float64(total)
+}
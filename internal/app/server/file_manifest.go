@@ -0,0 +1,108 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"swilly-delivery-service/internal/pkg/manifest"
+	"time"
+)
+
+// findManifest returns the path of the manifest written next to the file at path, if any.
+func findManifest(path string) (string, bool) {
+	for _, suffix := range manifest.Suffixes {
+		if _, err := os.Stat(path + suffix); err == nil {
+			return path + suffix, true
+		}
+	}
+	return "", false
+}
+
+// manifestDataPath returns the path of the file the manifest at path describes.
+func manifestDataPath(path string) string {
+	return strings.TrimSuffix(path, manifest.Suffix(path))
+}
+
+// loadManifest reads and validates the manifest of the file of the run, if any, and has the run send the
+// campaign it describes. It returns the folder the file belongs in and the reason why if the manifest is
+// invalid, or missing while required.
+func (fp *FileProcessor) loadManifest(run *fileRun) (string, string) {
+	path, found := findManifest(run.path)
+	if !found {
+		if fp.manifestRequired {
+			return failedFolder, fmt.Sprintf("no manifest received within %s", fp.manifestWait)
+		}
+		return "", ""
+	}
+
+	// Read once, so that the manifest verified is the one parsed
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return quarantineFolder, fmt.Sprintf("invalid manifest: %v", err)
+	}
+	if fp.signers != nil {
+		if _, err := fp.verifySignature(path, bytes.NewReader(data)); err != nil {
+			return rejectSignature("manifest", err)
+		}
+	}
+
+	m, err := manifest.Parse(path, data)
+	if err == nil {
+		err = m.Validate()
+	}
+	if err != nil {
+		return quarantineFolder, fmt.Sprintf("invalid manifest: %v", err)
+	}
+	run.manifest = m
+	run.campaign = m.Campaign
+	run.resend = run.resend || m.Resend
+	return "", ""
+}
+
+// checkManifest rejects files of expired campaigns, or whose line count is further from the expected row
+// count of their manifest than the tolerance, returning the folder they belong in and the reason why.
+func (fp *FileProcessor) checkManifest(run *fileRun) (string, string) {
+	m := run.manifest
+	if m == nil {
+		return "", ""
+	}
+	if m.Expired(run.summary.StartedAt) {
+		return quarantineFolder, fmt.Sprintf("campaign expired at %s", m.ExpiresAt.Format(time.RFC3339))
+	}
+
+	expected, lines := *m.ExpectedRows, run.summary.Lines
+	difference := lines - expected
+	if difference < 0 {
+		difference = -difference
+	}
+	if (expected == 0 && lines != 0) || percentOf(difference, expected) > fp.thresholds.rowCountTolerancePercent {
+		return quarantineFolder, fmt.Sprintf("%d rows, expected %d within %.2f%%", lines, expected, fp.thresholds.rowCountTolerancePercent)
+	}
+	return "", ""
+}
+
+// sendAtDelay returns in how many seconds from now the campaign of m is to be sent, 0 when it's not
+// scheduled or already due.
+func sendAtDelay(m *manifest.Manifest, now time.Time) int64 {
+	if m == nil || m.SendAt == nil || !now.Before(*m.SendAt) {
+		return 0
+	}
+	return int64(math.Ceil(m.SendAt.Sub(now).Seconds()))
+}
+
+// addManifestArgs adds what the manifest says of the campaign to the arguments of a job, the template
+// being the message to send.
+func addManifestArgs(args map[string]interface{}, m *manifest.Manifest) {
+	args["message"] = m.Template
+	args["owner"] = m.Owner
+	args["channel"] = m.Channel
+	args["priority"] = m.Priority
+	if m.SendAt != nil {
+		args["send_at"] = m.SendAt.Format(time.RFC3339)
+	}
+	if m.ExpiresAt != nil {
+		args["expires_at"] = m.ExpiresAt.Format(time.RFC3339)
+	}
+}
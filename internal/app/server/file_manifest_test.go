@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"swilly-delivery-service/internal/pkg/dedup"
+	"swilly-delivery-service/internal/pkg/manifest"
+	"swilly-delivery-service/internal/pkg/queue"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const diwaliManifest = `{
+	"campaign": "diwali_sale", "owner": "growth@swilly.in", "template": "Lights, sweets and 40% off",
+	"channel": "push", "send_at": "2025-10-20T09:00:00Z", "priority": 5, "expected_rows": 3
+}`
+
+func (f *FileProcessSuite) TestFileProcessor_ProcessFileWithManifest() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali")
+	f.NoError(os.WriteFile(filename, []byte("1\n2\n3\n"), 0644))
+	f.NoError(os.WriteFile(filename+manifest.JSONSuffix, []byte(diwaliManifest), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_diwali").Return(true, "", nil)
+	// The campaign named by the manifest is the one users are deduplicated within
+	f.expectScreened("diwali_sale", 3)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	var jobs []map[string]interface{}
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ string, args []map[string]interface{}) ([]*queue.Job, error) {
+			jobs = append(jobs, args...)
+			return nil, nil
+		})
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	f.Len(jobs, 3)
+	f.Equal(map[string]interface{}{
+		"userID": "1", "message": "Lights, sweets and 40% off", "filename": "swilly_diwali", "campaign": "diwali_sale",
+		"owner": "growth@swilly.in", "channel": "push", "priority": 5, "send_at": "2025-10-20T09:00:00Z",
+	}, jobs[0])
+	summary := f.readSummary(processedFolder, "swilly_diwali")
+	f.Equal(processedFolder, summary.Status)
+	f.Equal("growth@swilly.in", summary.Manifest.Owner)
+	_, err = os.Stat(filepath.Join(f.tmpDir, processedFolder, "swilly_diwali"+manifest.JSONSuffix))
+	f.NoError(err)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ProcessManifestResend() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali_again")
+	f.NoError(os.WriteFile(filename, []byte("1\n"), 0644))
+	f.NoError(os.WriteFile(filename+manifest.YAMLSuffix, []byte(
+		"campaign: diwali_sale\nowner: growth@swilly.in\ntemplate: Hi\nchannel: sms\nexpected_rows: 1\nresend: true\n"), 0644))
+
+	// Sent again although its content was, to users already sent the campaign
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_diwali_again").Return(false, "swilly_diwali", nil)
+	f.suppression.EXPECT().ContainsMany([]string{"1"}).Return([]bool{false}, nil)
+	f.dedup.EXPECT().CheckResendMany(gomock.Any(), "diwali_sale", []string{"1"}).Return([]dedup.Result{dedup.Unique}, nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	summary := f.readSummary(processedFolder, "swilly_diwali_again")
+	f.Equal(int64(1), summary.Enqueued)
+	f.True(summary.Manifest.Resend)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_SchedulesJobsUntilSendAt() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali")
+	f.NoError(os.WriteFile(filename, []byte("1\n2\n"), 0644))
+	sendAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	f.NoError(os.WriteFile(filename+manifest.YAMLSuffix, []byte(
+		"campaign: diwali_sale\nowner: growth@swilly.in\ntemplate: Hi\nchannel: sms\nexpected_rows: 2\nsend_at: "+sendAt+"\n"), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_diwali").Return(true, "", nil)
+	f.expectScreened("diwali_sale", 2)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	// Scheduled for the send_at instead of enqueued for the workers to defer
+	var delay int64
+	var jobs []map[string]interface{}
+	f.enqueuer.EXPECT().EnqueueBatchIn(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ string, secondsFromNow int64, args []map[string]interface{}) ([]*queue.Job, error) {
+			delay = secondsFromNow
+			jobs = append(jobs, args...)
+			return nil, nil
+		})
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	f.InDelta(3600, delay, 5)
+	f.Len(jobs, 2)
+	summary := f.readSummary(processedFolder, "swilly_diwali")
+	f.Equal(int64(2), summary.Enqueued)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_QuarantineRowCountMismatch() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali")
+	f.NoError(os.WriteFile(filename, []byte("1\n2\n3\n4\n"), 0644))
+	f.NoError(os.WriteFile(filename+manifest.YAMLSuffix, []byte(
+		"campaign: diwali_sale\nowner: growth@swilly.in\ntemplate: Hi\nchannel: sms\nexpected_rows: 3\n"), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_diwali").Return(true, "", nil)
+	f.checksums.EXPECT().Release(gomock.Any()).Return(nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.thresholds.rowCountTolerancePercent = 10
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	summary := f.readSummary(quarantineFolder, "swilly_diwali")
+	f.Equal("4 rows, expected 3 within 10.00%", summary.Reason)
+	f.Zero(summary.Enqueued)
+	_, err = os.Stat(filepath.Join(f.tmpDir, quarantineFolder, "swilly_diwali"+manifest.YAMLSuffix))
+	f.NoError(err)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_QuarantineInvalidManifest() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali")
+	f.NoError(os.WriteFile(filename, []byte("1\n"), 0644))
+	f.NoError(os.WriteFile(filename+manifest.JSONSuffix, []byte(`{"campaign": "diwali_sale", "channel": "fax"}`), 0644))
+
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	summary := f.readSummary(quarantineFolder, "swilly_diwali")
+	f.Equal("invalid manifest: owner is required; template is required; channel must be one of push, sms, email; "+
+		"expected_rows is required", summary.Reason)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_WaitForManifest() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali")
+	f.NoError(os.WriteFile(filename, []byte("1\n"), 0644))
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.manifestWait = 50 * time.Millisecond
+	fp.manifestRequired = true
+
+	// Left where it was dropped, and queued again once the wait is over
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+	_, err = os.Stat(filename)
+	f.NoError(err)
+	f.Eventually(func() bool { return fp.pending.len() == 1 }, time.Second, 10*time.Millisecond)
+
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+	summary := f.readSummary(failedFolder, "swilly_diwali")
+	f.Equal("no manifest received within 50ms", summary.Reason)
+}
+
+func TestFileHandler_GetManifest(t *testing.T) {
+	directory := t.TempDir()
+	require.NoError(t, createLifecycleFolders(directory))
+	path := filepath.Join(directory, processedFolder, "swilly_diwali"+manifest.JSONSuffix)
+	require.NoError(t, os.WriteFile(path, []byte(diwaliManifest), 0644))
+	handler := &fileHandler{directory: directory}
+
+	recorder := httptest.NewRecorder()
+	handler.handle(recorder, httptest.NewRequest(http.MethodGet, "/files/swilly_diwali/manifest", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{
+		"campaign": "diwali_sale", "owner": "growth@swilly.in", "template": "Lights, sweets and 40% off",
+		"channel": "push", "send_at": "2025-10-20T09:00:00Z", "priority": 5, "expected_rows": 3
+	}`, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	handler.handle(recorder, httptest.NewRequest(http.MethodGet, "/files/swilly_other/manifest", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
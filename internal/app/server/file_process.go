@@ -3,34 +3,111 @@ package server
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/app"
+	"swilly-delivery-service/internal/pkg/checksum"
+	"swilly-delivery-service/internal/pkg/dedup"
+	"swilly-delivery-service/internal/pkg/encryption"
+	"swilly-delivery-service/internal/pkg/lease"
 	"swilly-delivery-service/internal/pkg/log"
+	"swilly-delivery-service/internal/pkg/manifest"
+	"swilly-delivery-service/internal/pkg/profile"
+	"swilly-delivery-service/internal/pkg/queue"
+	"swilly-delivery-service/internal/pkg/recipient"
+	"swilly-delivery-service/internal/pkg/segment"
+	"swilly-delivery-service/internal/pkg/signature"
+	"swilly-delivery-service/internal/pkg/stats"
+	"swilly-delivery-service/internal/pkg/suppression"
 	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
-	"github.com/gocraft/work"
 	"go.uber.org/zap"
 )
 
+var (
+	errInvalidUserID  = errors.New("invalid user ID")
+	errUserSuppressed = errors.New("user is suppressed")
+	errDuplicateUser  = errors.New("duplicate user ID")
+	errUserInactive   = errors.New("user is inactive")
+)
+
 type Enqueuer interface {
-	Enqueue(jobName string, args map[string]interface{}) (*work.Job, error)
+	Enqueue(jobName string, args map[string]interface{}) (*queue.Job, error)
+	// EnqueueBatch enqueues a job for each of args in one round trip, all or none of them.
+	EnqueueBatch(jobName string, args []map[string]interface{}) ([]*queue.Job, error)
+	// EnqueueBatchIn schedules a job for each of args to be run secondsFromNow, like EnqueueBatch.
+	EnqueueBatchIn(jobName string, secondsFromNow int64, args []map[string]interface{}) ([]*queue.Job, error)
+}
+
+// unspooledEnqueuer is implemented by Enqueuers spooling to disk the jobs they can't enqueue, to enqueue
+// jobs that must not be written to disk, those of encrypted files.
+type unspooledEnqueuer interface {
+	EnqueueBatchUnspooled(jobName string, args []map[string]interface{}) ([]*queue.Job, error)
+	EnqueueBatchInUnspooled(jobName string, secondsFromNow int64, args []map[string]interface{}) ([]*queue.Job, error)
 }
 
 type FileProcessor struct {
-	directory string
-	watcher   *fsnotify.Watcher
-	wg        sync.WaitGroup
-	fileMutex sync.Map
-	enqueuer  Enqueuer
+	directory   string
+	watcher     *fsnotify.Watcher
+	wg          sync.WaitGroup
+	fileMutex   sync.Map
+	enqueuer    Enqueuer
+	suppression suppression.List
+	dedup       dedup.Tracker
+	recipients  recipient.Validator
+	segments    segment.Resolver
+	// signers are the keys files must be signed with, nil when signatures aren't required
+	signers       *signature.Keyring
+	signatureWait time.Duration
+	// decrypter decrypts the files encrypted with age, nil when encrypted files aren't accepted
+	decrypter *encryption.Decrypter
+	// profiles enriches users with their profile, looked up lookupBatchSize users at once. It's nil when
+	// enrichment is disabled.
+	profiles        profile.Client
+	lookupBatchSize int
+	inactivePolicy  string
+	checksums       checksum.Store
+	stats           stats.Store
+	leases          lease.Locker
+	thresholds      fileThresholds
+	// batchSize and flushInterval bound how many jobs of a file wait to be enqueued together, and for how long
+	batchSize     int
+	flushInterval time.Duration
+	// chunkSize is the size above which a file is split into chunks, chunkWorkers of which are
+	// processed at once
+	chunkSize    int64
+	chunkWorkers int
+	// pending holds the discovered files until one of the workers picks them up, once the backlog of
+	// the job queue is below highWaterMark
+	pending              *fileQueue
+	workers              int
+	backlog              queueDepth
+	highWaterMark        int64
+	backpressureInterval time.Duration
+	// processTimeout is how long a worker spends on a file before checkpointing it for a later run to
+	// carry on, 0 for no limit
+	processTimeout time.Duration
+	// manifestWait is how long a dropped file waits for its manifest, from when it's first seen as
+	// recorded in awaitingSidecars. Files without one are then failed if manifestRequired.
+	manifestWait     time.Duration
+	manifestRequired bool
+	awaitingSidecars sync.Map
+	// stopping is set once Stop is called, after which no new file is picked up
+	stopping bool
+	stopLock sync.Mutex
+	cancel   context.CancelFunc
 }
 
-func NewFileProcessor(directory string, enqueuer Enqueuer) (*FileProcessor, error) {
+func NewFileProcessor(directory string, enqueuer Enqueuer, dependency *app.Dependency) (*FileProcessor, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return nil, err
@@ -41,45 +118,91 @@ func NewFileProcessor(directory string, enqueuer Enqueuer) (*FileProcessor, erro
 		return nil, err
 	}
 
+	if err = createLifecycleFolders(directory); err != nil {
+		return nil, err
+	}
+
+	queueConfig := config.AppConfig.FileQueueConfig
 	return &FileProcessor{
-		directory: directory,
-		watcher:   watcher,
-		enqueuer:  enqueuer,
+		directory:            directory,
+		watcher:              watcher,
+		enqueuer:             enqueuer,
+		suppression:          dependency.Suppression,
+		dedup:                dependency.Dedup,
+		recipients:           dependency.Recipients,
+		segments:             dependency.Segments,
+		signers:              dependency.Signers,
+		signatureWait:        config.AppConfig.SignatureConfig.Wait,
+		decrypter:            dependency.Decrypter,
+		profiles:             dependency.Profiles,
+		lookupBatchSize:      config.AppConfig.EnrichmentConfig.BatchSize,
+		inactivePolicy:       config.AppConfig.EnrichmentConfig.InactivePolicy,
+		checksums:            dependency.Checksums,
+		stats:                dependency.Stats,
+		leases:               dependency.Leases,
+		thresholds:           newFileThresholds(),
+		batchSize:            config.AppConfig.EnqueueConfig.BatchSize,
+		flushInterval:        config.AppConfig.EnqueueConfig.FlushInterval,
+		chunkSize:            config.AppConfig.FileChunkConfig.Size,
+		chunkWorkers:         config.AppConfig.FileChunkConfig.Workers,
+		pending:              newFileQueue(queueConfig.Priorities),
+		workers:              queueConfig.Workers,
+		backlog:              dependency.Queue,
+		highWaterMark:        queueConfig.HighWaterMark,
+		backpressureInterval: queueConfig.BackpressureInterval,
+		processTimeout:       queueConfig.ProcessTimeout,
+		manifestWait:         config.AppConfig.ManifestConfig.Wait,
+		manifestRequired:     config.AppConfig.ManifestConfig.Required,
 	}, nil
 }
 
 func (fp *FileProcessor) Start(ctx context.Context) {
-	go fp.processDirectory(ctx, config.AppConfig.DirectoryPath)
+	fp.stopLock.Lock()
+	ctx, fp.cancel = context.WithCancel(ctx)
+	fp.stopLock.Unlock()
+
+	fp.startWorkers(ctx)
+	// Files left in the processing folder were claimed before a restart and never finished
+	fp.processDirectory(filepath.Join(config.AppConfig.DirectoryPath, processingFolder))
+	fp.processDirectory(config.AppConfig.DirectoryPath)
 	go fp.monitorDirectory(ctx)
+	go fp.recoverAbandonedFiles(ctx)
 }
 
-func (fp *FileProcessor) processDirectory(ctx context.Context, directory string) {
-	// Scan the directory and process each file
+// processDirectory queues the files of the directory to be processed.
+func (fp *FileProcessor) processDirectory(directory string) {
 	files, err := os.ReadDir(directory)
 	if err != nil {
 		return
 	}
 
 	for _, file := range files {
-		if !file.IsDir() && strings.Contains(file.Name(), "swilly") {
-			fp.wg.Add(1)
-			go fp.processFile(ctx, filepath.Join(directory, file.Name()))
+		if !file.IsDir() && isDataFile(file.Name()) {
+			fp.queueFile(filepath.Join(directory, file.Name()))
 		}
 	}
 }
 
+// monitorDirectory processes files as they are dropped, until the processor is stopped.
 func (fp *FileProcessor) monitorDirectory(ctx context.Context) {
-	defer fp.watcher.Close()
-
 	for {
 		select {
+		case <-ctx.Done():
+			return
 		case event, ok := <-fp.watcher.Events:
 			if !ok {
 				return
 			}
-			if strings.Contains(event.Name, "swilly") && (event.Op&fsnotify.Create == fsnotify.Create) {
-				fp.wg.Add(1)
-				go fp.processFile(ctx, event.Name)
+			if event.Op&fsnotify.Create != fsnotify.Create {
+				continue
+			}
+			name := filepath.Base(event.Name)
+			if isDataFile(name) {
+				fp.queueFile(event.Name)
+			}
+			// A file waiting for its sidecars is picked up as soon as one of them is dropped
+			if dataPath, ok := sidecarDataPath(event.Name); ok && isDataFile(filepath.Base(dataPath)) && fileExists(dataPath) {
+				fp.queueFile(dataPath)
 			}
 		case err, ok := <-fp.watcher.Errors:
 			if !ok {
@@ -90,65 +213,394 @@ func (fp *FileProcessor) monitorDirectory(ctx context.Context) {
 	}
 }
 
-// processFile reads the file, extracts user IDs, and processes the data.
+// recoverAbandonedFiles regularly picks up files left in the processing folder by another instance
+// that died, once their lease expired.
+func (fp *FileProcessor) recoverAbandonedFiles(ctx context.Context) {
+	ticker := time.NewTicker(fp.leases.TTL())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fp.processDirectory(filepath.Join(fp.directory, processingFolder))
+		}
+	}
+}
+
+// Stop stops picking up new files, interrupts the ones being processed and waits until they are
+// checkpointed or ctx is done.
+func (fp *FileProcessor) Stop(ctx context.Context) error {
+	fp.stopLock.Lock()
+	fp.stopping = true
+	if fp.cancel != nil {
+		fp.cancel()
+	}
+	fp.stopLock.Unlock()
+
+	if err := fp.watcher.Close(); err != nil {
+		log.Error("Error closing file watcher", zap.Error(err))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		fp.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("files still processing: %w", ctx.Err())
+	}
+}
+
+// queueFile queues a file for the workers, unless the processor is stopping.
+func (fp *FileProcessor) queueFile(filename string) {
+	fp.stopLock.Lock()
+	defer fp.stopLock.Unlock()
+	if fp.stopping {
+		return
+	}
+
+	fp.pending.push(filename)
+}
+
+// processFile claims the file, enqueues its user IDs and moves it to the folder matching the outcome.
 func (fp *FileProcessor) processFile(ctx context.Context, filename string) {
 	defer fp.wg.Done()
 
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+	if fp.processTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, fp.processTimeout)
+		defer cancel()
+	}
+
+	// Every instance sees the files dropped on the shared volume, only the one holding the lease
+	// processes it
+	name := filepath.Base(filename)
+	acquired, err := fp.leases.Acquire(name)
+	if err != nil {
+		log.Error("Error acquiring file lease", zap.String("filename", filename), zap.Error(err))
+		return
+	}
+	if !acquired {
+		log.Debug("File is processed elsewhere", zap.String("filename", filename))
+		return
+	}
+	defer fp.releaseLease(name)
+	ctx, stopLease := context.WithCancel(ctx)
+	defer stopLease()
+	go fp.keepLease(ctx, name, stopLease)
 
 	mutex, _ := fp.getFileMutex(filename)
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	file, err := os.Open(filename)
+	// The file may have been processed by whoever held the lease before
+	if _, err := os.Stat(filename); err != nil {
+		return
+	}
+	if fp.awaitSidecars(filename) {
+		return
+	}
+
+	path, err := fp.claimFile(filename)
 	if err != nil {
-		log.Error("Error opening file", zap.String("filename", filename), zap.Error(err))
+		log.Error("Error claiming file", zap.String("filename", filename), zap.Error(err))
 		return
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		if ctx.Err() != nil {
-			log.Error("Context deadline exceeded. Aborting processing")
+	run := newFileRun(path)
+	run.recovered = path == filename && filepath.Dir(path) == filepath.Join(fp.directory, processingFolder)
+	if folder, reason := fp.loadManifest(run); folder != "" {
+		fp.finishRun(run, folder, reason)
+		return
+	}
+	if isSegmentFile(run.filename) {
+		if folder, reason := fp.expandRun(run, membersPath(run.path)); folder != "" {
+			fp.finishRun(run, folder, reason)
 			return
 		}
+	}
+	if _, err := resumeRun(run); err != nil {
+		log.Error("Error reading checkpoint, processing file from the start", zap.String("filename", path), zap.Error(err))
+	}
+	if err := run.openReport(reportPath(path)); err != nil {
+		fp.finishRun(run, failedFolder, fmt.Sprintf("unable to create report: %v", err))
+		return
+	}
 
-		userID := scanner.Text()
-		if err = fp.processUserID(userID); err != nil {
-			log.Error("Error processing userID", zap.String("userID", userID), zap.String("filename", filename), zap.Error(err))
-			continue
+	folder, reason := fp.ingestFile(ctx, run)
+	if folder == processingFolder {
+		fp.checkpointRun(run, reason)
+		return
+	}
+	fp.finishRun(run, folder, reason)
+}
+
+// keepLease renews the lease on a file until ctx is done, cancelling the processing of the file if
+// the lease is lost so that it's checkpointed for the new holder to resume.
+func (fp *FileProcessor) keepLease(ctx context.Context, name string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(fp.leases.TTL() / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := fp.leases.Renew(name)
+			if err != nil {
+				// Keep trying until the lease expires, redis may be back by then
+				log.Error("Error renewing file lease", zap.String("filename", name), zap.Error(err))
+				continue
+			}
+			if !renewed && ctx.Err() == nil {
+				log.Error("Lost file lease, interrupting processing", zap.String("filename", name))
+				cancel()
+				return
+			}
 		}
+	}
+}
 
+func (fp *FileProcessor) releaseLease(name string) {
+	if err := fp.leases.Release(name); err != nil {
+		log.Error("Error releasing file lease", zap.String("filename", name), zap.Error(err))
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		log.Error("Error scanning file", zap.String("filename", filename), zap.Error(err))
+// ingestFile enqueues the user IDs of a claimed file, reporting rejected lines to the report of the run.
+// Large files are split into chunks processed in parallel. It returns the folder the file belongs in
+// and, unless it was processed, the reason why. A run interrupted by a shutdown stays in the processing
+// folder to be resumed after a restart.
+func (fp *FileProcessor) ingestFile(ctx context.Context, run *fileRun) (string, string) {
+	file, err := os.Open(run.source)
+	if err != nil {
+		return failedFolder, fmt.Sprintf("unable to open file: %v", err)
 	}
+	defer file.Close()
+	// The file of a segment run was verified when its segments were expanded
+	if run.source == run.path {
+		if folder, reason := fp.verifyRun(run, file); folder != "" {
+			return folder, reason
+		}
+	}
+	content, size, err := fp.openContent(run, file)
+	if err != nil {
+		return failedFolder, fmt.Sprintf("unable to decrypt file: %v", err)
+	}
+
+	// A resumed run already went through the checks below before being interrupted
+	if !run.resumed {
+		if folder, reason := fp.checkFile(run, content, size); folder != "" {
+			return folder, reason
+		}
+	}
+
+	if folder, reason := fp.ingestChunks(ctx, run, content); folder != "" {
+		return folder, reason
+	}
+
+	failed := percentOf(run.summary.Failed, run.summary.Lines)
+	if failed > fp.thresholds.failedEnqueuePercent {
+		return failedFolder, fmt.Sprintf("%.2f%% of lines could not be enqueued, more than the %.2f%% threshold", failed, fp.thresholds.failedEnqueuePercent)
+	}
+	return processedFolder, ""
+}
 
-	// Move the processed file to processed folder
-	err = os.Rename(filename, filepath.Join(fp.directory, "processed", filepath.Base(filename)))
+// checkFile rejects files already processed or with too many invalid lines, returning the folder
+// they belong in and the reason why.
+func (fp *FileProcessor) checkFile(run *fileRun, content io.ReaderAt, size int64) (string, string) {
+	if err := fp.inspectFile(run, io.NewSectionReader(content, 0, size)); err != nil {
+		return failedFolder, fmt.Sprintf("unable to read file: %v", err)
+	}
+
+	claimed, previous, err := fp.checksums.Claim(run.checksum, run.filename)
 	if err != nil {
-		log.Error("Error moving file", zap.String("filename", filename), zap.Error(err))
+		return failedFolder, fmt.Sprintf("unable to check for duplicate file: %v", err)
 	}
+	// A file recovered after a crash was claimed by the run that never finished
+	run.claimed = claimed || (run.recovered && previous == run.filename)
+	if !run.claimed {
+		if !run.resend {
+			return duplicatesFolder, fmt.Sprintf("duplicate of %s (sha256 %s)", previous, run.checksum)
+		}
+		log.Info("Processing resend of an already processed file", zap.String("filename", run.filename), zap.String("original", previous))
+	}
+
+	invalid := percentOf(run.summary.Invalid, run.summary.Lines)
+	if invalid > fp.thresholds.quarantineInvalidPercent {
+		return quarantineFolder, fmt.Sprintf("%.2f%% of lines are invalid, more than the %.2f%% threshold", invalid, fp.thresholds.quarantineInvalidPercent)
+	}
+	return fp.checkManifest(run)
 }
 
-func (fp *FileProcessor) processUserID(userID string) error {
-	log.Info("Processing UserID", zap.String("userID", userID))
+// inspectFile reads the whole content of the file once to compute its checksum, count and report its
+// invalid lines and split it into chunks. The checksum of an encrypted file is that of its plaintext, so
+// that the same content encrypted twice is still a duplicate.
+func (fp *FileProcessor) inspectFile(run *fileRun, content io.Reader) error {
+	hash := sha256.New()
+	chunks := &chunker{size: fp.chunkSize}
+	scanner := bufio.NewScanner(io.TeeReader(content, hash))
+	scanner.Split(chunks.scanLines)
+	for scanner.Scan() {
+		run.summary.Lines++
+		userID := recipient.Normalize(scanner.Text())
+		if err := fp.validateUserID(userID); err != nil {
+			run.summary.Invalid++
+			run.reject(run.summary.Lines, userID, errInvalidUserID)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
 
-	if _, err := strconv.Atoi(userID); err != nil {
-		return fmt.Errorf("invalid user ID: %s", userID)
+	run.checksum = hex.EncodeToString(hash.Sum(nil))
+	run.chunks = chunks.chunks
+	return nil
+}
+
+// screenUsers sets why each of users is skipped, if they are. Suppressed users and duplicates are
+// looked up for all users at once, rather than one round trip per user.
+func (fp *FileProcessor) screenUsers(run *fileRun, users []*parsedUser) {
+	pending := make([]*parsedUser, 0, len(users))
+	for _, user := range users {
+		if user.skipErr = fp.screenUser(run, user); user.skipErr == nil {
+			pending = append(pending, user)
+		}
 	}
+	pending = fp.screenSuppressed(run, pending)
+	fp.screenDuplicates(run, pending)
+}
 
-	_, err := fp.enqueuer.Enqueue(config.AppConfig.JobName, work.Q{"userID": userID, "message": "message"})
-	if err != nil {
-		log.Error("unable to queue information in redis", zap.String("userID", userID), zap.Error(err))
+// screenUser checks what can be told of the user on its own.
+func (fp *FileProcessor) screenUser(run *fileRun, user *parsedUser) error {
+	if err := fp.validateUserID(user.userID); err != nil {
 		return err
 	}
+
+	if user.lookupErr != nil {
+		return fmt.Errorf("unable to look up user profile: %w", user.lookupErr)
+	}
+	if fp.isInactive(user) && fp.inactivePolicy == config.InactivePolicyDrop {
+		log.Info("Skipping inactive userID", run.userIDField(user.userID), zap.String("filename", run.filename))
+		return errUserInactive
+	}
 	return nil
 }
 
+// screenSuppressed skips the users in the suppression list, returning the others.
+func (fp *FileProcessor) screenSuppressed(run *fileRun, users []*parsedUser) []*parsedUser {
+	if len(users) == 0 {
+		return users
+	}
+
+	suppressed, err := fp.suppression.ContainsMany(userIDsOf(users))
+	unsuppressed := users[:0]
+	for i, user := range users {
+		switch {
+		case err != nil:
+			user.skipErr = fmt.Errorf("unable to check suppression list: %w", err)
+		case suppressed[i]:
+			log.Info("Skipping suppressed userID", run.userIDField(user.userID), zap.String("filename", run.filename))
+			user.skipErr = errUserSuppressed
+		default:
+			unsuppressed = append(unsuppressed, user)
+		}
+	}
+	return unsuppressed
+}
+
+// screenDuplicates skips the users already seen in the file or campaign, marking the others as seen.
+func (fp *FileProcessor) screenDuplicates(run *fileRun, users []*parsedUser) {
+	if len(users) == 0 {
+		return
+	}
+
+	check := fp.dedup.CheckMany
+	if run.resend {
+		check = fp.dedup.CheckResendMany
+	}
+	seen, err := check(run.id, run.campaign, userIDsOf(users))
+	for i, user := range users {
+		switch {
+		case err != nil:
+			user.skipErr = fmt.Errorf("unable to check duplicates: %w", err)
+		case seen[i] != dedup.Unique:
+			log.Info("Skipping duplicate userID", run.userIDField(user.userID), zap.String("filename", run.filename), zap.Bool("inFile", seen[i] == dedup.DuplicateInFile))
+			user.skipErr = errDuplicateUser
+		}
+	}
+}
+
+// processUserID adds the job of the user read from the file to batch, unless screenUsers skipped the user.
+func (fp *FileProcessor) processUserID(run *fileRun, batch *jobBatch, user *parsedUser) error {
+	userID := user.userID
+	log.Info("Processing UserID", run.userIDField(userID))
+
+	if user.skipErr != nil {
+		return user.skipErr
+	}
+
+	args := map[string]interface{}{
+		"userID":   userID,
+		"message":  "message",
+		"filename": run.filename,
+		"campaign": run.campaign,
+	}
+	if fp.profiles != nil {
+		args["active"] = !fp.isInactive(user)
+	}
+	if user.profile != nil {
+		args["locale"] = user.profile.Locale
+		args["timezone"] = user.profile.Timezone
+		args["channels"] = user.profile.Channels
+	}
+	if run.manifest != nil {
+		addManifestArgs(args, run.manifest)
+	}
+	batch.add(user.line, userID, args)
+	return nil
+}
+
+// userIDsOf returns the user IDs of users.
+func userIDsOf(users []*parsedUser) []string {
+	userIDs := make([]string, len(users))
+	for i, user := range users {
+		userIDs[i] = user.userID
+	}
+	return userIDs
+}
+
+// isDataFile tells whether a file holds user or segment IDs to process, as opposed to a signature, manifest,
+// report, checkpoint or segment members written alongside.
+func isDataFile(name string) bool {
+	return strings.Contains(name, "swilly") && !strings.HasSuffix(name, reportSuffix) && !strings.HasSuffix(name, checkpointSuffix) &&
+		!strings.HasSuffix(name, membersSuffix) && !strings.HasSuffix(name, signatureSuffix) && !manifest.IsManifest(name)
+}
+
+// validateUserID checks a normalized userID against the recipient ID rule.
+func (fp *FileProcessor) validateUserID(userID string) error {
+	if err := fp.recipients.Validate(userID); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidUserID, err)
+	}
+	return nil
+}
+
+// isResend tells whether support explicitly named the file as a resend of a processed one.
+func isResend(name string) bool {
+	return strings.Contains(strings.ToLower(name), "resend")
+}
+
+// campaignFromFilename names the campaign a file belongs to after the file itself, without extension.
+func campaignFromFilename(filename string) string {
+	filename = trimEncryptionSuffix(filename)
+	return strings.TrimSuffix(filename, filepath.Ext(filename))
+}
+
 func (fp *FileProcessor) getFileMutex(filename string) (*sync.Mutex, bool) {
 	mutex, loaded := fp.fileMutex.LoadOrStore(filename, &sync.Mutex{})
 	return mutex.(*sync.Mutex), loaded
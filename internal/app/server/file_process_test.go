@@ -1,12 +1,25 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"strconv"
 	"swilly-delivery-service/internal/app"
+	"swilly-delivery-service/internal/app/worker"
+	"swilly-delivery-service/internal/pkg/checksum"
+	"swilly-delivery-service/internal/pkg/dedup"
+	"swilly-delivery-service/internal/pkg/frequency"
+	"swilly-delivery-service/internal/pkg/lease"
+	"swilly-delivery-service/internal/pkg/queue"
+	"swilly-delivery-service/internal/pkg/recipient"
+	"swilly-delivery-service/internal/pkg/stats"
+	"swilly-delivery-service/internal/pkg/suppression"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,8 +30,14 @@ import (
 
 type FileProcessSuite struct {
 	suite.Suite
-	enqueuer *MockEnqueuer
-	tmpDir   string
+	enqueuer    *MockEnqueuer
+	suppression *suppression.MockList
+	dedup       *dedup.MockTracker
+	checksums   *checksum.MockStore
+	stats       *stats.MockStore
+	leases      *lease.MockLocker
+	dependency  *app.Dependency
+	tmpDir      string
 }
 
 func (f *FileProcessSuite) SetupTest() {
@@ -26,8 +45,18 @@ func (f *FileProcessSuite) SetupTest() {
 
 	controller := gomock.NewController(f.T())
 	f.enqueuer = NewMockEnqueuer(controller)
-	f.tmpDir, _ = os.MkdirTemp("/", "example")
-	defer os.RemoveAll(f.tmpDir) // clean up
+	f.suppression = suppression.NewMockList(controller)
+	f.dedup = dedup.NewMockTracker(controller)
+	f.checksums = checksum.NewMockStore(controller)
+	f.stats = stats.NewMockStore(controller)
+	f.leases = lease.NewMockLocker(controller)
+	recipients, _ := recipient.NewIntValidator("", "")
+	f.dependency = &app.Dependency{Suppression: f.suppression, Dedup: f.dedup, Checksums: f.checksums, Stats: f.stats, Leases: f.leases, Queue: queue.NewMemoryBackend(1), Recipients: recipients}
+	f.tmpDir, _ = os.MkdirTemp("", "example")
+}
+
+func (f *FileProcessSuite) TearDownTest() {
+	os.RemoveAll(f.tmpDir) // clean up
 }
 
 func TestFileProcess(t *testing.T) {
@@ -35,28 +64,66 @@ func TestFileProcess(t *testing.T) {
 }
 
 func (f *FileProcessSuite) TestNewFileProcessor() {
-	processor, err := NewFileProcessor(f.tmpDir, f.enqueuer)
+	processor, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
 	f.NotNil(processor)
 	f.Nil(err)
 }
 
 func (f *FileProcessSuite) TestFileProcessor_ProcessValidUserID() {
-	processor, err := NewFileProcessor(f.tmpDir, f.enqueuer)
-	f.enqueuer.EXPECT().Enqueue(gomock.Any(), gomock.Any()).Return(nil, nil)
+	processor, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.suppression.EXPECT().ContainsMany([]string{"2"}).Return([]bool{false}, nil)
+	f.dedup.EXPECT().CheckMany(gomock.Any(), "swilly_file", []string{"2"}).Return([]dedup.Result{dedup.Unique}, nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-	err = processor.processUserID("2")
+	run := newFileRun("swilly_file")
+	batch := newJobBatch(10, time.Minute)
+	user := &parsedUser{line: 1, userID: "2"}
+	processor.screenUsers(run, []*parsedUser{user})
+	err = processor.processUserID(run, batch, user)
 	f.NoError(err)
+	f.False(batch.due())
+
+	processor.flushBatch(run, &run.summary, batch)
+	f.Equal(int64(1), run.summary.Enqueued)
 }
 
 func (f *FileProcessSuite) TestFileProcessor_ProcessInvalidUserID() {
-	processor, err := NewFileProcessor(f.tmpDir, f.enqueuer)
+	processor, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
 
-	err = processor.processUserID("invalid")
+	run := newFileRun("swilly_file")
+	user := &parsedUser{line: 1, userID: "invalid"}
+	processor.screenUsers(run, []*parsedUser{user})
+	err = processor.processUserID(run, newJobBatch(10, time.Minute), user)
 	f.Error(err)
 	assert.Contains(f.T(), err.Error(), "invalid user ID")
 }
 
+func (f *FileProcessSuite) TestFileProcessor_ProcessSuppressedUserID() {
+	processor, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.suppression.EXPECT().ContainsMany([]string{"2"}).Return([]bool{true}, nil)
+
+	run := newFileRun("swilly_file")
+	user := &parsedUser{line: 1, userID: "2"}
+	processor.screenUsers(run, []*parsedUser{user})
+	err = processor.processUserID(run, newJobBatch(10, time.Minute), user)
+	f.True(errors.Is(err, errUserSuppressed))
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ProcessDuplicateUserID() {
+	processor, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.suppression.EXPECT().ContainsMany([]string{"2"}).Return([]bool{false}, nil)
+	f.dedup.EXPECT().CheckMany(gomock.Any(), "swilly_file", []string{"2"}).Return([]dedup.Result{dedup.DuplicateInCampaign}, nil)
+
+	run := newFileRun("swilly_file")
+	user := &parsedUser{line: 1, userID: "2"}
+	processor.screenUsers(run, []*parsedUser{user})
+	err = processor.processUserID(run, newJobBatch(10, time.Minute), user)
+	f.True(errors.Is(err, errDuplicateUser))
+}
+
 func (f *FileProcessSuite) TestFileProcessor_ProcessDirectory() {
+	f.grantLeases()
 	// Create some temporary files in the directory
 	for i := 0; i < 3; i++ {
 		filename := filepath.Join(f.tmpDir, "swilly_file_"+strconv.Itoa(i))
@@ -65,12 +132,22 @@ func (f *FileProcessSuite) TestFileProcessor_ProcessDirectory() {
 		defer file.Close()
 	}
 
-	processor, _ := NewFileProcessor(f.tmpDir, f.enqueuer)
+	processor, _ := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil).AnyTimes()
+	f.checksums.EXPECT().Claim(gomock.Any(), gomock.Any()).Return(true, "", nil).AnyTimes()
 
-	processor.processDirectory(context.Background(), f.tmpDir)
+	processor.processDirectory(f.tmpDir)
+	f.Equal(3, processor.pending.len())
 
-	// Allow goroutines to finish processing
-	time.Sleep(100 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	processor.startWorkers(ctx)
+	f.Eventually(func() bool {
+		files, _ := os.ReadDir(filepath.Join(f.tmpDir, processedFolder))
+		return len(files) == 3*3
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	processor.wg.Wait()
 }
 
 func (f *FileProcessSuite) TestFileProcessor_GetFileMutex() {
@@ -90,28 +167,396 @@ func (f *FileProcessSuite) TestFileProcessor_GetFileMutex() {
 }
 
 func (f *FileProcessSuite) TestFileProcessor_ProcessFile() {
+	f.grantLeases()
 	filename := filepath.Join(f.tmpDir, "swilly_test_file")
 	file, err := os.Create(filename)
 	f.NoError(err)
 	defer file.Close()
 
-	processedDir := filepath.Join(f.tmpDir, "processed")
-	err = os.Mkdir(processedDir, 0755)
-	f.NoError(err)
-	defer os.RemoveAll(processedDir)
-
-	data := "123\n456\n789"
+	data := "123\n456\n789\ninvalid\n123"
 	_, err = file.WriteString(data)
 	f.NoError(err)
 
-	f.enqueuer.EXPECT().Enqueue(gomock.Any(), gomock.Any()).Times(3).Return(nil, nil)
+	// The invalid line is left out of the checks
+	f.suppression.EXPECT().ContainsMany([]string{"123", "456", "789", "123"}).Return([]bool{false, false, true, false}, nil)
+	f.dedup.EXPECT().CheckMany(gomock.Any(), "swilly_test_file", []string{"123", "456", "123"}).
+		Return([]dedup.Result{dedup.Unique, dedup.Unique, dedup.DuplicateInFile}, nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), []map[string]interface{}{
+		{"userID": "123", "message": "message", "filename": "swilly_test_file", "campaign": "swilly_test_file"},
+		{"userID": "456", "message": "message", "filename": "swilly_test_file", "campaign": "swilly_test_file"},
+	}).Return(nil, nil)
+	scope := stats.FileScope(filepath.Base(filename))
+	f.stats.EXPECT().Incr(scope, "enqueued", int64(2)).Return(nil)
+	f.stats.EXPECT().Incr(scope, "suppressed", int64(1)).Return(nil)
+	f.stats.EXPECT().Incr(scope, "duplicates", int64(1)).Return(nil)
+	f.stats.EXPECT().Incr(scope, "inactive", int64(0)).Return(nil)
+	f.stats.EXPECT().Incr(scope, "invalid", int64(1)).Return(nil)
+	f.stats.EXPECT().Incr(scope, "failed", int64(0)).Return(nil)
+	f.stats.EXPECT().Incr(scope, "spooled", int64(0)).Return(nil)
 
-	fp := &FileProcessor{directory: f.tmpDir, enqueuer: f.enqueuer}
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.thresholds.quarantineInvalidPercent = 50
 	fp.wg.Add(1)
 	fp.processFile(context.Background(), filename)
 
 	// Check if the file was moved to the processed directory
-	processedFilename := filepath.Join(processedDir, filepath.Base(filename))
+	processedFilename := filepath.Join(f.tmpDir, processedFolder, filepath.Base(filename))
 	_, err = os.Stat(processedFilename)
 	f.NoError(err)
+
+	// Check the rejected lines report and summary written next to it
+	report, err := os.ReadFile(processedFilename + ".report.csv")
+	f.NoError(err)
+	f.Equal("line,value,reason\n4,invalid,invalid user ID\n", string(report))
+
+	summary := f.readSummary(processedFolder, "swilly_test_file")
+	f.Equal(processedFolder, summary.Status)
+	f.Equal(int64(5), summary.Lines)
+	f.Equal(int64(2), summary.Enqueued)
+	f.Equal(int64(1), summary.Duplicates)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_NormalizeUserIDs() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("\uFEFFu_123\r\n u_456 \r\n42\r\n"), 0644))
+
+	f.expectScreened("swilly_test_file", 2)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), []map[string]interface{}{
+		{"userID": "u_123", "message": "message", "filename": "swilly_test_file", "campaign": "swilly_test_file"},
+		{"userID": "u_456", "message": "message", "filename": "swilly_test_file", "campaign": "swilly_test_file"},
+	}).Return(nil, nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	f.dependency.Recipients, _ = recipient.NewPrefixValidator([]string{"u_"})
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.thresholds.quarantineInvalidPercent = 50
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	report, err := os.ReadFile(filepath.Join(f.tmpDir, processedFolder, "swilly_test_file.report.csv"))
+	f.NoError(err)
+	f.Equal("line,value,reason\n3,42,invalid user ID\n", string(report))
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ProcessDuplicateFile() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("123\n456\n"), 0644))
+
+	sum := "3b1250f7f2b7851fe4853ae48424d0923848bd973a053b8dff44da2c8f348878"
+	f.checksums.EXPECT().Claim(sum, "swilly_test_file").Return(false, "swilly_original_file", nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	_, err = os.Stat(filepath.Join(f.tmpDir, duplicatesFolder, "swilly_test_file"))
+	f.NoError(err)
+	reason, err := os.ReadFile(filepath.Join(f.tmpDir, duplicatesFolder, "swilly_test_file.reason"))
+	f.NoError(err)
+	f.Contains(string(reason), "duplicate of swilly_original_file")
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ProcessResendFile() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file_resend")
+	f.NoError(os.WriteFile(filename, []byte("123"), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file_resend").Return(false, "swilly_test_file", nil)
+	f.suppression.EXPECT().ContainsMany([]string{"123"}).Return([]bool{false}, nil)
+	f.dedup.EXPECT().CheckResendMany(gomock.Any(), "swilly_test_file_resend", []string{"123"}).Return([]dedup.Result{dedup.Unique}, nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	_, err = os.Stat(filepath.Join(f.tmpDir, processedFolder, "swilly_test_file_resend"))
+	f.NoError(err)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_QuarantineFile() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("123\ninvalid\n"), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.checksums.EXPECT().Release(gomock.Any()).Return(nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.thresholds.quarantineInvalidPercent = 5
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	_, err = os.Stat(filepath.Join(f.tmpDir, quarantineFolder, "swilly_test_file"))
+	f.NoError(err)
+	summary := f.readSummary(quarantineFolder, "swilly_test_file")
+	f.Equal(int64(0), summary.Enqueued)
+	f.Contains(summary.Reason, "50.00% of lines are invalid")
+}
+
+func (f *FileProcessSuite) TestFileProcessor_FailFile() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("123\n456\n"), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.checksums.EXPECT().Release(gomock.Any()).Return(nil)
+	f.expectScreened(gomock.Any(), 2)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, errors.New("connection refused"))
+	// Not to be skipped as a duplicate when the file is dropped again
+	f.dedup.EXPECT().Forget("swilly_test_file", []string{"456"}).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.thresholds.failedEnqueuePercent = 0
+	fp.batchSize = 1
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	_, err = os.Stat(filepath.Join(f.tmpDir, failedFolder, "swilly_test_file"))
+	f.NoError(err)
+	report, err := os.ReadFile(filepath.Join(f.tmpDir, failedFolder, "swilly_test_file.report.csv"))
+	f.NoError(err)
+	f.Equal("line,value,reason\n2,456,connection refused\n", string(report))
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ResumeInterruptedFile() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("123\n456\n"), 0644))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Claimed once only, the resumed run carries on from the first
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.expectScreened(gomock.Any(), 2)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(string, []map[string]interface{}) ([]*queue.Job, error) {
+			cancel()
+			return nil, nil
+		})
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.batchSize = 1
+	fp.lookupBatchSize = 1
+	fp.wg.Add(1)
+	fp.processFile(ctx, filename)
+
+	processingFilename := filepath.Join(f.tmpDir, processingFolder, "swilly_test_file")
+	_, err = os.Stat(checkpointPath(processingFilename))
+	f.NoError(err)
+
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), processingFilename)
+
+	_, err = os.Stat(checkpointPath(processingFilename))
+	f.True(os.IsNotExist(err))
+	summary := f.readSummary(processedFolder, "swilly_test_file")
+	f.Equal(int64(2), summary.Lines)
+	f.Equal(int64(2), summary.Enqueued)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_CheckpointFileOnTimeout() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("123\n456\n"), 0644))
+
+	// The checksum is kept for the run resuming the file
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.expectScreened(gomock.Any(), 2)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(string, []map[string]interface{}) ([]*queue.Job, error) {
+			time.Sleep(100 * time.Millisecond)
+			return nil, nil
+		})
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.batchSize = 1
+	fp.lookupBatchSize = 1
+	fp.processTimeout = 50 * time.Millisecond
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	processingFilename := filepath.Join(f.tmpDir, processingFolder, "swilly_test_file")
+	data, err := os.ReadFile(checkpointPath(processingFilename))
+	f.NoError(err)
+	var checkpoint fileCheckpoint
+	f.NoError(json.Unmarshal(data, &checkpoint))
+	f.Equal(int64(1), checkpoint.Progress)
+	_, err = os.Stat(filepath.Join(f.tmpDir, failedFolder, "swilly_test_file"))
+	f.True(os.IsNotExist(err))
+
+	fp.processTimeout = time.Minute
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), processingFilename)
+
+	summary := f.readSummary(processedFolder, "swilly_test_file")
+	f.Equal(int64(2), summary.Lines)
+	f.Equal(int64(2), summary.Enqueued)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_Stop() {
+	f.grantLeases()
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.Start(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	f.NoError(fp.Stop(ctx))
+
+	// Files dropped after stopping are left for the next start
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("123\n"), 0644))
+	fp.queueFile(filename)
+	f.Zero(fp.pending.len())
+	_, err = os.Stat(filename)
+	f.NoError(err)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_SkipFileLeasedElsewhere() {
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("123\n"), 0644))
+	f.leases.EXPECT().Acquire("swilly_test_file").Return(false, nil)
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	// Left for the instance holding the lease
+	_, err = os.Stat(filename)
+	f.NoError(err)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_DeliversThroughMemoryQueue() {
+	f.grantLeases()
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("123\n456\n"), 0644))
+
+	limiter := frequency.NewMockLimiter(gomock.NewController(f.T()))
+	f.dependency.Frequency = limiter
+	f.dependency.Queue = queue.NewMemoryBackend(1)
+	f.expectScreened("swilly_test_file", 2)
+	// The worker checks the suppression list again before delivering
+	f.suppression.EXPECT().Contains(gomock.Any()).Return(false, nil).AnyTimes()
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	delivered := make(chan string, 2)
+	limiter.EXPECT().Allow(gomock.Any()).DoAndReturn(func(userID string) (frequency.Decision, error) {
+		delivered <- userID
+		return frequency.Decision{Allowed: true}, nil
+	}).Times(2)
+
+	w := worker.NewWorker(f.dependency)
+	f.NoError(w.Start(context.Background()))
+	defer w.Stop(context.Background())
+
+	fp, err := NewFileProcessor(f.tmpDir, f.dependency.Queue, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	var userIDs []string
+	for len(userIDs) < 2 {
+		select {
+		case userID := <-delivered:
+			userIDs = append(userIDs, userID)
+		case <-time.After(time.Second):
+			f.FailNow("jobs were not delivered", "delivered %v", userIDs)
+		}
+	}
+	f.ElementsMatch([]string{"123", "456"}, userIDs)
+}
+
+// grantLeases lets the processor take the lease on every file.
+func (f *FileProcessSuite) grantLeases() {
+	f.leases.EXPECT().Acquire(gomock.Any()).Return(true, nil).AnyTimes()
+	f.leases.EXPECT().Renew(gomock.Any()).Return(true, nil).AnyTimes()
+	f.leases.EXPECT().Release(gomock.Any()).Return(nil).AnyTimes()
+	f.leases.EXPECT().TTL().Return(time.Minute).AnyTimes()
+}
+
+// expectScreened expects users users of campaign to be screened in batches, none of them suppressed or
+// duplicates.
+func (f *FileProcessSuite) expectScreened(campaign interface{}, users int) {
+	var suppressionChecks, dedupChecks int64
+	f.suppression.EXPECT().ContainsMany(gomock.Any()).DoAndReturn(func(userIDs []string) ([]bool, error) {
+		atomic.AddInt64(&suppressionChecks, int64(len(userIDs)))
+		return make([]bool, len(userIDs)), nil
+	}).AnyTimes()
+	f.dedup.EXPECT().CheckMany(gomock.Any(), campaign, gomock.Any()).DoAndReturn(func(_, _ string, userIDs []string) ([]dedup.Result, error) {
+		atomic.AddInt64(&dedupChecks, int64(len(userIDs)))
+		return make([]dedup.Result, len(userIDs)), nil
+	}).AnyTimes()
+
+	t := f.T()
+	t.Cleanup(func() {
+		assert.Equal(t, int64(users), atomic.LoadInt64(&suppressionChecks), "users checked for suppression")
+		assert.Equal(t, int64(users), atomic.LoadInt64(&dedupChecks), "users checked for duplicates")
+	})
+}
+
+func (f *FileProcessSuite) readSummary(folder, name string) fileSummary {
+	var summary fileSummary
+	data, err := os.ReadFile(filepath.Join(f.tmpDir, folder, name+".summary.json"))
+	f.NoError(err)
+	f.NoError(json.Unmarshal(data, &summary))
+	return summary
+}
+
+func (f *FileProcessSuite) TestValidateFile() {
+	filename := filepath.Join(f.tmpDir, "swilly_test_file")
+	f.NoError(os.WriteFile(filename, []byte("123\n456\n789\n123\n"), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_test_file").Return(true, "", nil)
+	f.suppression.EXPECT().ContainsMany([]string{"123", "456", "789", "123"}).Return([]bool{false, true, false, false}, nil)
+	f.dedup.EXPECT().CheckMany(gomock.Any(), "swilly_test_file", []string{"123", "789", "123"}).
+		Return([]dedup.Result{dedup.Unique, dedup.Unique, dedup.DuplicateInFile}, nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+
+	var report bytes.Buffer
+	result, err := ValidateFile(context.Background(), filename, f.dependency, &report)
+	f.NoError(err)
+
+	f.Equal(processedFolder, result.Summary.Status)
+	f.Equal(int64(2), result.Summary.Enqueued)
+	f.Equal(int64(1), result.Summary.Suppressed)
+	f.Equal(int64(1), result.Summary.Duplicates)
+	f.Len(result.SampleJobs, 2)
+	f.Equal("123", result.SampleJobs[0]["userID"])
+	f.Equal("line,value,reason\n", report.String())
+
+	// The file is left where it is
+	_, err = os.Stat(filename)
+	f.NoError(err)
 }
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"container/heap"
+	"context"
+	"path/filepath"
+	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/pkg/log"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// pendingFile is a file waiting for a file worker.
+type pendingFile struct {
+	path     string
+	priority int
+	// seq orders files of equal priority by arrival
+	seq uint64
+}
+
+// pendingFiles is a heap of files, of highest priority then earliest arrival first.
+type pendingFiles []*pendingFile
+
+func (p pendingFiles) Len() int { return len(p) }
+
+func (p pendingFiles) Less(i, j int) bool {
+	if p[i].priority != p[j].priority {
+		return p[i].priority > p[j].priority
+	}
+	return p[i].seq < p[j].seq
+}
+
+func (p pendingFiles) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+
+func (p *pendingFiles) Push(x interface{}) { *p = append(*p, x.(*pendingFile)) }
+
+func (p *pendingFiles) Pop() interface{} {
+	old := *p
+	file := old[len(old)-1]
+	*p = old[:len(old)-1]
+	return file
+}
+
+// fileQueue holds the files waiting to be processed, each once however many times it's discovered.
+type fileQueue struct {
+	priorities []config.FilePriority
+
+	mutex  sync.Mutex
+	files  pendingFiles
+	queued map[string]bool
+	seq    uint64
+	// ready is signalled when files are pushed, to wake up a worker waiting for one
+	ready chan struct{}
+}
+
+func newFileQueue(priorities []config.FilePriority) *fileQueue {
+	return &fileQueue{priorities: priorities, queued: make(map[string]bool), ready: make(chan struct{}, 1)}
+}
+
+// push queues the file at path unless it's already waiting.
+func (q *fileQueue) push(path string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.queued[path] {
+		return
+	}
+	q.queued[path] = true
+	q.seq++
+	heap.Push(&q.files, &pendingFile{path: path, priority: q.priority(path), seq: q.seq})
+	q.signal()
+}
+
+// priority returns the priority of the first pattern matching the name of the file, 0 if none does.
+func (q *fileQueue) priority(path string) int {
+	name := filepath.Base(path)
+	for _, priority := range q.priorities {
+		if matched, _ := filepath.Match(priority.Pattern, name); matched {
+			return priority.Priority
+		}
+	}
+	return 0
+}
+
+func (q *fileQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for a file until ctx is done.
+func (q *fileQueue) pop(ctx context.Context) (string, bool) {
+	for {
+		q.mutex.Lock()
+		if len(q.files) > 0 {
+			file := heap.Pop(&q.files).(*pendingFile)
+			delete(q.queued, file.path)
+			if len(q.files) > 0 {
+				// Pass the wake up on to the next worker
+				q.signal()
+			}
+			q.mutex.Unlock()
+			return file.path, true
+		}
+		q.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return "", false
+		case <-q.ready:
+		}
+	}
+}
+
+func (q *fileQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.files)
+}
+
+// queueDepth tells how many jobs are waiting in the job queue.
+type queueDepth interface {
+	Depth(jobName string) (int64, error)
+}
+
+// startWorkers starts the file workers, which process the pending files one at a time until ctx is done.
+func (fp *FileProcessor) startWorkers(ctx context.Context) {
+	for i := 0; i < fp.workers; i++ {
+		fp.wg.Add(1)
+		go fp.work(ctx)
+	}
+}
+
+func (fp *FileProcessor) work(ctx context.Context) {
+	defer fp.wg.Done()
+
+	for {
+		if !fp.waitForBacklog(ctx) {
+			return
+		}
+		filename, ok := fp.pending.pop(ctx)
+		if !ok {
+			return
+		}
+		fp.wg.Add(1)
+		fp.processFile(ctx, filename)
+	}
+}
+
+// waitForBacklog holds off picking up a file while the job queue is above the high-water mark, so that
+// workers catch up before more jobs are enqueued. It returns false if ctx is done meanwhile.
+func (fp *FileProcessor) waitForBacklog(ctx context.Context) bool {
+	if fp.highWaterMark <= 0 {
+		return ctx.Err() == nil
+	}
+
+	waiting := false
+	for {
+		depth, err := fp.backlog.Depth(config.AppConfig.JobName)
+		if err != nil {
+			// Enqueueing will retry and spool, there is no point holding files back
+			log.Error("unable to check job queue depth", zap.Error(err))
+		}
+		if err != nil || depth <= fp.highWaterMark {
+			if waiting {
+				log.Info("Job queue below the high-water mark, resuming files", zap.Int64("depth", depth))
+			}
+			return ctx.Err() == nil
+		}
+		if !waiting {
+			log.Info("Job queue above the high-water mark, holding files back",
+				zap.Int64("depth", depth),
+				zap.Int64("highWaterMark", fp.highWaterMark),
+				zap.Int("pendingFiles", fp.pending.len()),
+			)
+			waiting = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(fp.backpressureInterval):
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"swilly-delivery-service/config"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileQueue_PopsByPriorityThenArrival(t *testing.T) {
+	queue := newFileQueue([]config.FilePriority{{Pattern: "urgent_*", Priority: 10}})
+	queue.push("/drop/swilly_file_0")
+	queue.push("/drop/urgent_file_0")
+	queue.push("/drop/swilly_file_1")
+	queue.push("/drop/urgent_file_1")
+	// Discovered again while waiting
+	queue.push("/drop/swilly_file_0")
+	require.Equal(t, 4, queue.len())
+
+	var order []string
+	for queue.len() > 0 {
+		path, ok := queue.pop(context.Background())
+		require.True(t, ok)
+		order = append(order, path)
+	}
+	assert.Equal(t, []string{"/drop/urgent_file_0", "/drop/urgent_file_1", "/drop/swilly_file_0", "/drop/swilly_file_1"}, order)
+}
+
+func TestFileQueue_PopWaitsForFiles(t *testing.T) {
+	queue := newFileQueue(nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		queue.push("/drop/swilly_file_0")
+	}()
+	path, ok := queue.pop(context.Background())
+	assert.True(t, ok)
+	assert.Equal(t, "/drop/swilly_file_0", path)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, ok = queue.pop(ctx)
+	assert.False(t, ok)
+}
+
+// drainingQueue is a job queue whose depth drops by 100 at every check.
+type drainingQueue struct {
+	depth atomic.Int64
+}
+
+func (q *drainingQueue) Depth(string) (int64, error) {
+	return q.depth.Add(-100) + 100, nil
+}
+
+func TestFileProcessor_WaitForBacklog(t *testing.T) {
+	backlog := &drainingQueue{}
+	backlog.depth.Store(300)
+	fp := &FileProcessor{pending: newFileQueue(nil), backlog: backlog, highWaterMark: 100, backpressureInterval: time.Millisecond}
+
+	assert.True(t, fp.waitForBacklog(context.Background()))
+	assert.EqualValues(t, 0, backlog.depth.Load())
+
+	// Gives up once stopped
+	backlog.depth.Store(1000)
+	fp.backpressureInterval = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, fp.waitForBacklog(ctx))
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"swilly-delivery-service/internal/pkg/encryption"
+	"swilly-delivery-service/internal/pkg/log"
+	"swilly-delivery-service/internal/pkg/manifest"
+	"swilly-delivery-service/internal/pkg/stats"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fileSummary holds the outcome counts of processing a single file.
+type fileSummary struct {
+	Filename   string    `json:"filename"`
+	Status     string    `json:"status"`
+	Reason     string    `json:"reason,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Lines      int64     `json:"lines"`
+	Enqueued   int64     `json:"enqueued"`
+	// Spooled counts the jobs written to the local spool while redis was unavailable
+	Spooled    int64 `json:"spooled"`
+	Invalid    int64 `json:"invalid"`
+	Suppressed int64 `json:"suppressed"`
+	Duplicates int64 `json:"duplicates"`
+	// Inactive counts the users dropped because their account is inactive or unknown
+	Inactive int64 `json:"inactive"`
+	Failed   int64 `json:"failed"`
+	// Manifest describes the campaign of the file, if it came with one
+	Manifest *manifest.Manifest `json:"manifest,omitempty"`
+	// SignedBy is the identity of the approved key the file was signed with, if signatures are required
+	SignedBy string `json:"signed_by,omitempty"`
+	// Encrypted is set when the file was encrypted, in which case its report leaves out the rejected user IDs
+	Encrypted bool `json:"encrypted,omitempty"`
+}
+
+// fileRun is a single attempt at processing a file.
+type fileRun struct {
+	id       string
+	path     string
+	filename string
+	// source is the file the user IDs are read from, the file itself unless it lists segments
+	source   string
+	campaign string
+	checksum string
+	// manifest describes the campaign of the file, nil if it came without one
+	manifest *manifest.Manifest
+	// signer is the identity of the key the file was signed with
+	signer string
+	// resend is set when the file or its manifest says it's deliberately sent again, in which case it's
+	// processed even if its content was and its users aren't deduplicated across the campaign
+	resend bool
+	// encrypted is set when the file is encrypted with age, in which case the user IDs it holds are
+	// neither logged nor reported
+	encrypted bool
+	// claimed is set when this run recorded the checksum of the file
+	claimed bool
+	// recovered is set when the file was found in the processing folder after a restart
+	recovered bool
+	// resumed is set when the run continues one interrupted by a shutdown
+	resumed bool
+	// chunks are the parts of the file processed in parallel, each with the number of its lines
+	// already handled
+	chunks  []*fileChunk
+	summary fileSummary
+	// reportMutex guards report, written to by the chunks processed in parallel
+	reportMutex sync.Mutex
+	report      *csv.Writer
+	reportFile  *os.File
+}
+
+func newFileRun(filename string) *fileRun {
+	name := filepath.Base(filename)
+	now := time.Now()
+	return &fileRun{
+		id:        fmt.Sprintf("%s:%d", name, now.UnixNano()),
+		path:      filename,
+		filename:  name,
+		source:    filename,
+		campaign:  campaignFromFilename(name),
+		resend:    isResend(name),
+		encrypted: encryption.IsEncrypted(name),
+		summary:   fileSummary{Filename: name, Encrypted: encryption.IsEncrypted(name), StartedAt: now},
+	}
+}
+
+// progress returns the number of lines already handled.
+func (r *fileRun) progress() int64 {
+	var progress int64
+	for _, chunk := range r.chunks {
+		progress += chunk.Progress
+	}
+	return progress
+}
+
+// add adds the outcome counts of counts to the summary.
+func (s *fileSummary) add(counts fileSummary) {
+	s.Enqueued += counts.Enqueued
+	s.Spooled += counts.Spooled
+	s.Invalid += counts.Invalid
+	s.Suppressed += counts.Suppressed
+	s.Duplicates += counts.Duplicates
+	s.Inactive += counts.Inactive
+	s.Failed += counts.Failed
+}
+
+// openReport starts the report of lines rejected or failed during the run in a new file, or carries
+// on with the report of the interrupted run it resumes.
+func (r *fileRun) openReport(path string) error {
+	if !r.resumed {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		r.reportFile = file
+		return r.startReport(file)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	r.reportFile = file
+	r.report = csv.NewWriter(file)
+	return nil
+}
+
+// startReport starts the report of lines rejected or failed during the run.
+func (r *fileRun) startReport(w io.Writer) error {
+	r.report = csv.NewWriter(w)
+	return r.report.Write([]string{"line", "value", "reason"})
+}
+
+// reject adds a line to the report. The value of lines of encrypted files is left out, the report being
+// written in plaintext.
+func (r *fileRun) reject(line int64, value string, reason error) {
+	r.reportMutex.Lock()
+	defer r.reportMutex.Unlock()
+	if r.report == nil {
+		return
+	}
+	if r.encrypted {
+		value = ""
+	}
+	if err := r.report.Write([]string{strconv.FormatInt(line, 10), value, reason.Error()}); err != nil {
+		log.Error("unable to write report", zap.String("filename", r.filename), zap.Error(err))
+	}
+}
+
+func (r *fileRun) closeReport() error {
+	if r.report == nil {
+		return nil
+	}
+
+	r.report.Flush()
+	err := r.report.Error()
+	if r.reportFile == nil {
+		return err
+	}
+	if closeErr := r.reportFile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// finishRun closes the report of the run, moves the file to folder, writes its summary next to it and
+// records its counters.
+func (fp *FileProcessor) finishRun(run *fileRun, folder, reason string) {
+	summary := run.summary
+	summary.FinishedAt = time.Now()
+	summary.Status = folder
+	summary.Reason = reason
+	summary.Manifest = run.manifest
+	summary.SignedBy = run.signer
+	log.Info("Processed file",
+		zap.String("filename", run.filename),
+		zap.String("status", folder),
+		zap.String("reason", reason),
+		zap.Int64("enqueued", summary.Enqueued),
+		zap.Int64("spooled", summary.Spooled),
+		zap.Int64("invalid", summary.Invalid),
+		zap.Int64("suppressed", summary.Suppressed),
+		zap.Int64("duplicates", summary.Duplicates),
+		zap.Int64("inactive", summary.Inactive),
+		zap.Int64("failed", summary.Failed),
+	)
+
+	if err := run.closeReport(); err != nil {
+		log.Error("unable to write report", zap.String("filename", run.filename), zap.Error(err))
+	}
+	if err := fp.settleFile(run.path, folder, reason); err != nil {
+		log.Error("Error moving file", zap.String("filename", run.filename), zap.String("folder", folder), zap.Error(err))
+	}
+	if err := fp.writeSummary(folder, summary); err != nil {
+		log.Error("unable to write summary", zap.String("filename", run.filename), zap.Error(err))
+	}
+
+	// Let support drop the same content again once they fixed whatever stopped it from being processed
+	if run.claimed && (folder == failedFolder || folder == quarantineFolder) {
+		if err := fp.checksums.Release(run.checksum); err != nil {
+			log.Error("unable to release file checksum", zap.String("filename", run.filename), zap.Error(err))
+		}
+	}
+
+	if err := fp.dedup.Release(run.id); err != nil {
+		log.Error("unable to release dedup state", zap.String("filename", run.filename), zap.Error(err))
+	}
+
+	scope := stats.FileScope(run.filename)
+	counts := map[string]int64{
+		"enqueued":   summary.Enqueued,
+		"spooled":    summary.Spooled,
+		"invalid":    summary.Invalid,
+		"suppressed": summary.Suppressed,
+		"duplicates": summary.Duplicates,
+		"inactive":   summary.Inactive,
+		"failed":     summary.Failed,
+	}
+	for field, count := range counts {
+		if err := fp.stats.Incr(scope, field, count); err != nil {
+			log.Error("unable to record file stats", zap.String("filename", run.filename), zap.Error(err))
+			return
+		}
+	}
+}
+
+func (fp *FileProcessor) writeSummary(folder string, summary fileSummary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(fp.directory, folder, summary.Filename+".summary.json"), data, 0644)
+}
+
+const reportSuffix = ".report.csv"
+
+func reportPath(path string) string {
+	return path + reportSuffix
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"swilly-delivery-service/internal/pkg/encryption"
+	"swilly-delivery-service/internal/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// A file named with segmentSuffix lists segment IDs, one per line optionally written as
+// "segment: <ID>", instead of user IDs. The members of the segments are written to a file next to it,
+// named with membersSuffix, and processed in its place.
+const (
+	segmentSuffix = ".segments"
+	membersSuffix = ".members"
+	segmentPrefix = "segment:"
+)
+
+var (
+	errNoSegmentResolver = errors.New("no segment resolver is configured")
+	// Segment files hold no user IDs, and their members are written to disk
+	errEncryptedSegments = errors.New("segment files are not decrypted, drop them unencrypted")
+)
+
+func isSegmentFile(name string) bool {
+	return strings.HasSuffix(trimEncryptionSuffix(name), segmentSuffix)
+}
+
+func membersPath(path string) string {
+	return path + membersSuffix
+}
+
+// expandRun verifies the file of the run and has the run read the members of the segments it lists,
+// written to members. The members are only resolved once, a run resumed after a restart reads those of
+// the interrupted one. It returns the folder the file belongs in and the reason why if it can't be expanded.
+func (fp *FileProcessor) expandRun(run *fileRun, members string) (string, string) {
+	file, err := os.Open(run.path)
+	if err != nil {
+		return failedFolder, fmt.Sprintf("unable to expand segments: %v", err)
+	}
+	defer file.Close()
+	if folder, reason := fp.verifyRun(run, file); folder != "" {
+		return folder, reason
+	}
+
+	if _, err := os.Stat(members); err != nil {
+		if err := fp.expandSegments(run.path, file, members); err != nil {
+			return failedFolder, fmt.Sprintf("unable to expand segments: %v", err)
+		}
+	}
+	run.source = members
+	return "", ""
+}
+
+// expandSegments writes the members of the segments listed in file, the segment file at path, to dest,
+// one per line. dest is written through a temporary file, so that it's either complete or missing.
+func (fp *FileProcessor) expandSegments(path string, file io.Reader, dest string) error {
+	if fp.segments == nil {
+		return errNoSegmentResolver
+	}
+	if encryption.IsEncrypted(path) {
+		return errEncryptedSegments
+	}
+
+	// Not named like a data file, so that it's not picked up if left over by a crash
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "segment-members-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		segmentID := strings.TrimSpace(scanner.Text())
+		if len(segmentID) >= len(segmentPrefix) && strings.EqualFold(segmentID[:len(segmentPrefix)], segmentPrefix) {
+			segmentID = strings.TrimSpace(segmentID[len(segmentPrefix):])
+		}
+		if segmentID == "" {
+			continue
+		}
+		if err := fp.writeMembers(writer, segmentID); err != nil {
+			tmp.Close()
+			return fmt.Errorf("segment %s: %w", segmentID, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// writeMembers streams the members of a segment to w, one per line.
+func (fp *FileProcessor) writeMembers(w *bufio.Writer, segmentID string) error {
+	members, err := fp.segments.Members(segmentID)
+	if err != nil {
+		return err
+	}
+	defer members.Close()
+
+	var count int64
+	scanner := bufio.NewScanner(members)
+	for scanner.Scan() {
+		if _, err := w.WriteString(scanner.Text() + "\n"); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	log.Info("Expanded segment", zap.String("segment", segmentID), zap.Int64("members", count))
+	return nil
+}
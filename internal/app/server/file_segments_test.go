@@ -0,0 +1,96 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"swilly-delivery-service/internal/pkg/dedup"
+	"swilly-delivery-service/internal/pkg/segment"
+
+	"github.com/golang/mock/gomock"
+)
+
+// useSegmentStore has the dependencies resolve segments from a store of the churn-risk-7d and vip
+// segments.
+func (f *FileProcessSuite) useSegmentStore() {
+	store := filepath.Join(f.tmpDir, "segments")
+	f.NoError(os.Mkdir(store, 0755))
+	f.NoError(os.WriteFile(filepath.Join(store, "churn-risk-7d"), []byte("1\n2\n3"), 0644))
+	f.NoError(os.WriteFile(filepath.Join(store, "vip"), []byte("3\n4\n"), 0644))
+	f.dependency.Segments = segment.NewFileResolver(store)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ProcessSegmentFile() {
+	f.grantLeases()
+	f.useSegmentStore()
+	filename := filepath.Join(f.tmpDir, "swilly_promo.segments")
+	f.NoError(os.WriteFile(filename, []byte("segment: churn-risk-7d\n\nvip\n"), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_promo.segments").Return(true, "", nil)
+	f.suppression.EXPECT().ContainsMany(gomock.Any()).Return(make([]bool, 5), nil)
+	f.dedup.EXPECT().CheckMany(gomock.Any(), "swilly_promo", []string{"1", "2", "3", "3", "4"}).
+		Return([]dedup.Result{dedup.Unique, dedup.Unique, dedup.Unique, dedup.DuplicateInFile, dedup.Unique}, nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	var userIDs []string
+	f.recordUserIDs(&userIDs, nil)
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	f.Equal([]string{"1", "2", "3", "4"}, userIDs)
+	summary := f.readSummary(processedFolder, "swilly_promo.segments")
+	f.Equal(int64(5), summary.Lines)
+	f.Equal(int64(4), summary.Enqueued)
+	f.Equal(int64(1), summary.Duplicates)
+
+	// The members are kept along with the file
+	members, err := os.ReadFile(filepath.Join(f.tmpDir, processedFolder, "swilly_promo.segments.members"))
+	f.NoError(err)
+	f.Equal("1\n2\n3\n3\n4\n", string(members))
+}
+
+func (f *FileProcessSuite) TestFileProcessor_FailUnknownSegment() {
+	f.grantLeases()
+	f.useSegmentStore()
+	filename := filepath.Join(f.tmpDir, "swilly_promo.segments")
+	f.NoError(os.WriteFile(filename, []byte("vip\nlapsed-30d\n"), 0644))
+
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+
+	summary := f.readSummary(failedFolder, "swilly_promo.segments")
+	f.Equal("unable to expand segments: segment lapsed-30d: unknown segment: lapsed-30d", summary.Reason)
+	_, err = os.Stat(filepath.Join(f.tmpDir, failedFolder, "swilly_promo.segments.members"))
+	f.True(os.IsNotExist(err))
+	leftovers, err := filepath.Glob(filepath.Join(f.tmpDir, processingFolder, "*"))
+	f.NoError(err)
+	f.Empty(leftovers)
+}
+
+func (f *FileProcessSuite) TestValidateSegmentFile() {
+	f.useSegmentStore()
+	filename := filepath.Join(f.tmpDir, "swilly_promo.segments")
+	f.NoError(os.WriteFile(filename, []byte("vip\n"), 0644))
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_promo.segments").Return(true, "", nil)
+	f.expectScreened("swilly_promo", 2)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+
+	var report bytes.Buffer
+	result, err := ValidateFile(context.Background(), filename, f.dependency, &report)
+	f.NoError(err)
+
+	f.Equal(processedFolder, result.Summary.Status)
+	f.Equal(int64(2), result.Summary.Enqueued)
+	f.Len(result.SampleJobs, 2)
+	f.Equal("3", result.SampleJobs[0]["userID"])
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"swilly-delivery-service/internal/pkg/log"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// A file can be dropped along with sidecars: its signature, its manifest and the signature of the
+// manifest. They move along with the file from folder to folder.
+
+// sidecarPaths returns the paths of the sidecars of the file at path that exist.
+func sidecarPaths(path string) []string {
+	var paths []string
+	if fileExists(signaturePath(path)) {
+		paths = append(paths, signaturePath(path))
+	}
+	if manifestPath, found := findManifest(path); found {
+		paths = append(paths, manifestPath)
+		if fileExists(signaturePath(manifestPath)) {
+			paths = append(paths, signaturePath(manifestPath))
+		}
+	}
+	return paths
+}
+
+// sidecarDataPath returns the path of the file the sidecar at path was dropped along with, if it is one.
+func sidecarDataPath(path string) (string, bool) {
+	dataPath := manifestDataPath(strings.TrimSuffix(path, signatureSuffix))
+	return dataPath, dataPath != path
+}
+
+// moveSidecars moves the sidecars of the file at path next to the file at dest.
+func moveSidecars(path, dest string) error {
+	for _, sidecar := range sidecarPaths(path) {
+		if err := os.Rename(sidecar, dest+strings.TrimPrefix(sidecar, path)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// awaitingFile is a dropped file waiting for its sidecars.
+type awaitingFile struct {
+	firstSeen time.Time
+	// requeueAt is when the file is queued again, once the wait is over
+	requeueAt time.Time
+}
+
+// awaitSidecars tells whether the file dropped at filename has to wait for its manifest or signatures,
+// for up to manifestWait and signatureWait after it was first seen. The file is queued again once the
+// wait is over, or as soon as one of them is dropped.
+func (fp *FileProcessor) awaitSidecars(filename string) bool {
+	if filepath.Dir(filename) == filepath.Join(fp.directory, processingFolder) {
+		return false
+	}
+	wait := fp.sidecarWait(filename)
+	if wait <= 0 {
+		fp.awaitingSidecars.Delete(filename)
+		return false
+	}
+
+	value, _ := fp.awaitingSidecars.LoadOrStore(filename, &awaitingFile{firstSeen: time.Now()})
+	awaiting := value.(*awaitingFile)
+	requeueAt := awaiting.firstSeen.Add(wait)
+	if !time.Now().Before(requeueAt) {
+		fp.awaitingSidecars.Delete(filename)
+		return false
+	}
+	// The file may now be missing a sidecar with a longer wait than the one it was waiting for
+	if requeueAt.After(awaiting.requeueAt) {
+		awaiting.requeueAt = requeueAt
+		log.Info("Waiting for manifest or signature", zap.String("filename", filename), zap.Duration("wait", time.Until(requeueAt)))
+		time.AfterFunc(time.Until(requeueAt), func() { fp.queueFile(filename) })
+	}
+	return true
+}
+
+// sidecarWait returns how long after it was first seen the file at filename waits for the sidecars it's
+// missing, 0 if it's missing none worth waiting for.
+func (fp *FileProcessor) sidecarWait(filename string) time.Duration {
+	var wait time.Duration
+	manifestPath, hasManifest := findManifest(filename)
+	if !hasManifest && fp.manifestWait > wait {
+		wait = fp.manifestWait
+	}
+	unsigned := !fileExists(signaturePath(filename)) || (hasManifest && !fileExists(signaturePath(manifestPath)))
+	if fp.signers != nil && unsigned && fp.signatureWait > wait {
+		wait = fp.signatureWait
+	}
+	return wait
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"swilly-delivery-service/internal/pkg/log"
+	"swilly-delivery-service/internal/pkg/signature"
+
+	"go.uber.org/zap"
+)
+
+const signatureSuffix = signature.Suffix
+
+var errMissingSignature = errors.New("missing signature")
+
+func signaturePath(path string) string {
+	return path + signatureSuffix
+}
+
+// verifyRun checks that the file of the run was signed with an approved key, when signatures are
+// required, and records its signer. The signature is checked against file, the file of the run as opened
+// to be read, so that the content verified is the content processed. It returns the folder the file
+// belongs in and the reason why if not.
+func (fp *FileProcessor) verifyRun(run *fileRun, file *os.File) (string, string) {
+	if fp.signers == nil {
+		return "", ""
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return rejectSignature("file", err)
+	}
+	signer, err := fp.verifySignature(run.path, io.NewSectionReader(file, 0, info.Size()))
+	if err != nil {
+		return rejectSignature("file", err)
+	}
+	run.signer = signer
+	return "", ""
+}
+
+// verifySignature checks the detached signature of the file at path against the approved keys, over
+// content read from the file, and returns its signer. The outcome is recorded in the audit log.
+func (fp *FileProcessor) verifySignature(path string, content io.Reader) (string, error) {
+	name := filepath.Base(path)
+	signer, err := fp.readSignature(path, content)
+	switch {
+	case errors.Is(err, errMissingSignature) || errors.Is(err, signature.ErrInvalidSignature):
+		log.Audit("Rejected file signature", zap.String("filename", name), zap.Error(err))
+	case err != nil:
+		log.Error("Error verifying file signature", zap.String("filename", name), zap.Error(err))
+	default:
+		log.Audit("Verified file signature", zap.String("filename", name), zap.String("signer", signer))
+	}
+	return signer, err
+}
+
+func (fp *FileProcessor) readSignature(path string, content io.Reader) (string, error) {
+	sig, err := os.ReadFile(signaturePath(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", errMissingSignature
+	}
+	if err != nil {
+		return "", err
+	}
+	return fp.signers.Verify(content, sig)
+}
+
+// rejectSignature returns the folder a file whose signature, or that of its manifest, could not be
+// verified belongs in and the reason why. what names the file that was verified.
+func rejectSignature(what string, err error) (string, string) {
+	if errors.Is(err, errMissingSignature) || errors.Is(err, signature.ErrInvalidSignature) {
+		return quarantineFolder, fmt.Sprintf("rejected %s signature: %v", what, err)
+	}
+	return failedFolder, fmt.Sprintf("unable to verify %s signature: %v", what, err)
+}
@@ -0,0 +1,193 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"swilly-delivery-service/internal/pkg/manifest"
+	"swilly-delivery-service/internal/pkg/signature"
+	"time"
+
+	"github.com/golang/mock/gomock"
+)
+
+// useSigners has files signed by alice approved, returning her key.
+func (f *FileProcessSuite) useSigners() ed25519.PrivateKey {
+	_, alice, err := ed25519.GenerateKey(nil)
+	f.NoError(err)
+	f.dependency.Signers, err = signature.ParseKeyring(strings.NewReader(signature.KeyringLine("alice", alice)))
+	f.NoError(err)
+	return alice
+}
+
+func (f *FileProcessSuite) sign(key ed25519.PrivateKey, path string) {
+	file, err := os.Open(path)
+	f.NoError(err)
+	defer file.Close()
+	sig, err := signature.Sign(key, file)
+	f.NoError(err)
+	f.NoError(os.WriteFile(signaturePath(path), sig, 0644))
+}
+
+// processSignedFile processes the file at filename without waiting for its signature.
+func (f *FileProcessSuite) processSignedFile(filename string) {
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.signatureWait = 0
+	fp.wg.Add(1)
+	fp.processFile(context.Background(), filename)
+}
+
+func (f *FileProcessSuite) TestFileProcessor_ProcessSignedFile() {
+	f.grantLeases()
+	alice := f.useSigners()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali")
+	f.NoError(os.WriteFile(filename, []byte("1\n2\n3\n"), 0644))
+	f.NoError(os.WriteFile(filename+manifest.JSONSuffix, []byte(diwaliManifest), 0644))
+	f.sign(alice, filename)
+	f.sign(alice, filename+manifest.JSONSuffix)
+
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_diwali").Return(true, "", nil)
+	f.expectScreened("diwali_sale", 3)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+	f.enqueuer.EXPECT().EnqueueBatch(gomock.Any(), gomock.Any()).Return(nil, nil)
+	f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	f.processSignedFile(filename)
+
+	summary := f.readSummary(processedFolder, "swilly_diwali")
+	f.Equal(processedFolder, summary.Status)
+	f.Equal("alice", summary.SignedBy)
+	for _, sidecar := range []string{signatureSuffix, manifest.JSONSuffix, manifest.JSONSuffix + signatureSuffix} {
+		_, err := os.Stat(filepath.Join(f.tmpDir, processedFolder, "swilly_diwali"+sidecar))
+		f.NoError(err, sidecar)
+	}
+}
+
+func (f *FileProcessSuite) TestFileProcessor_QuarantineUnverifiedFiles() {
+	alice := f.useSigners()
+	_, mallory, err := ed25519.GenerateKey(nil)
+	f.NoError(err)
+
+	cases := map[string]struct {
+		prepare func(filename string)
+		reason  string
+	}{
+		"unsigned": {
+			prepare: func(string) {},
+			reason:  "rejected file signature: missing signature",
+		},
+		"tampered": {
+			prepare: func(filename string) {
+				f.sign(alice, filename)
+				f.NoError(os.WriteFile(filename, []byte("1\n2\n3\n"), 0644))
+			},
+			reason: "rejected file signature: signature does not match any approved key",
+		},
+		"unapproved": {
+			prepare: func(filename string) { f.sign(mallory, filename) },
+			reason:  "rejected file signature: signature does not match any approved key",
+		},
+		"unsigned manifest": {
+			prepare: func(filename string) {
+				f.sign(alice, filename)
+				f.NoError(os.WriteFile(filename+manifest.JSONSuffix, []byte(diwaliManifest), 0644))
+			},
+			reason: "rejected manifest signature: missing signature",
+		},
+	}
+	for name, c := range cases {
+		f.Run(name, func() {
+			f.grantLeases()
+			f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+			f.stats.EXPECT().Incr(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			filename := filepath.Join(f.tmpDir, "swilly_"+strings.ReplaceAll(name, " ", "_"))
+			f.NoError(os.WriteFile(filename, []byte("1\n2\n"), 0644))
+			c.prepare(filename)
+
+			f.processSignedFile(filename)
+
+			summary := f.readSummary(quarantineFolder, filepath.Base(filename))
+			f.Equal(c.reason, summary.Reason)
+			f.Zero(summary.Enqueued)
+		})
+	}
+}
+
+func (f *FileProcessSuite) TestFileProcessor_AwaitSignature() {
+	alice := f.useSigners()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali")
+	f.NoError(os.WriteFile(filename, []byte("1\n"), 0644))
+
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+	fp.signatureWait = time.Minute
+	f.True(fp.awaitSidecars(filename))
+
+	// A manifest dropped without its signature is waited for too
+	f.sign(alice, filename)
+	f.NoError(os.WriteFile(filename+manifest.YAMLSuffix, []byte("campaign: diwali_sale\n"), 0644))
+	f.True(fp.awaitSidecars(filename))
+
+	f.sign(alice, filename+manifest.YAMLSuffix)
+	f.False(fp.awaitSidecars(filename))
+}
+
+func (f *FileProcessSuite) TestFileProcessor_VerifyRunReadsOpenedFile() {
+	alice := f.useSigners()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali")
+	f.NoError(os.WriteFile(filename, []byte("1\n2\n3\n"), 0644))
+	f.sign(alice, filename)
+	fp, err := NewFileProcessor(f.tmpDir, f.enqueuer, f.dependency)
+	f.NoError(err)
+
+	// The file is swapped once opened, the content read from the opened file is what's verified
+	signed, err := os.Open(filename)
+	f.NoError(err)
+	defer signed.Close()
+	tampered := filepath.Join(f.tmpDir, "tampered")
+	f.NoError(os.WriteFile(tampered, []byte("1\n2\n666\n"), 0644))
+	f.NoError(os.Rename(tampered, filename))
+	run := newFileRun(filename)
+	folder, reason := fp.verifyRun(run, signed)
+	f.Empty(folder, reason)
+	f.Equal("alice", run.signer)
+
+	swapped, err := os.Open(filename)
+	f.NoError(err)
+	defer swapped.Close()
+	folder, reason = fp.verifyRun(newFileRun(filename), swapped)
+	f.Equal(quarantineFolder, folder)
+	f.Equal("rejected file signature: signature does not match any approved key", reason)
+}
+
+func (f *FileProcessSuite) TestValidateSignedFile() {
+	alice := f.useSigners()
+	filename := filepath.Join(f.tmpDir, "swilly_diwali")
+	f.NoError(os.WriteFile(filename, []byte("1\n2\n3\n"), 0644))
+	f.NoError(os.WriteFile(filename+manifest.JSONSuffix, []byte(diwaliManifest), 0644))
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil).AnyTimes()
+
+	// Unsigned sidecars are rejected as the real run would
+	result, err := ValidateFile(context.Background(), filename, f.dependency, io.Discard)
+	f.NoError(err)
+	f.Equal(quarantineFolder, result.Summary.Status)
+	f.Equal("rejected manifest signature: missing signature", result.Summary.Reason)
+
+	f.sign(alice, filename+manifest.JSONSuffix)
+	result, err = ValidateFile(context.Background(), filename, f.dependency, io.Discard)
+	f.NoError(err)
+	f.Equal(quarantineFolder, result.Summary.Status)
+	f.Equal("rejected file signature: missing signature", result.Summary.Reason)
+
+	f.sign(alice, filename)
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_diwali").Return(true, "", nil)
+	f.expectScreened("diwali_sale", 3)
+	result, err = ValidateFile(context.Background(), filename, f.dependency, io.Discard)
+	f.NoError(err)
+	f.Equal(processedFolder, result.Summary.Status)
+	f.Equal("alice", result.Summary.SignedBy)
+}
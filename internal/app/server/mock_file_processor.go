@@ -6,8 +6,8 @@ package server
 
 import (
 	reflect "reflect"
+	queue "swilly-delivery-service/internal/pkg/queue"
 
-	work "github.com/gocraft/work"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -35,10 +35,10 @@ func (m *MockEnqueuer) EXPECT() *MockEnqueuerMockRecorder {
 }
 
 // Enqueue mocks base method.
-func (m *MockEnqueuer) Enqueue(arg0 string, arg1 map[string]interface{}) (*work.Job, error) {
+func (m *MockEnqueuer) Enqueue(arg0 string, arg1 map[string]interface{}) (*queue.Job, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Enqueue", arg0, arg1)
-	ret0, _ := ret[0].(*work.Job)
+	ret0, _ := ret[0].(*queue.Job)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -48,3 +48,33 @@ func (mr *MockEnqueuerMockRecorder) Enqueue(arg0, arg1 interface{}) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Enqueue", reflect.TypeOf((*MockEnqueuer)(nil).Enqueue), arg0, arg1)
 }
+
+// EnqueueBatch mocks base method.
+func (m *MockEnqueuer) EnqueueBatch(arg0 string, arg1 []map[string]interface{}) ([]*queue.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueBatch", arg0, arg1)
+	ret0, _ := ret[0].([]*queue.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueBatch indicates an expected call of EnqueueBatch.
+func (mr *MockEnqueuerMockRecorder) EnqueueBatch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueBatch", reflect.TypeOf((*MockEnqueuer)(nil).EnqueueBatch), arg0, arg1)
+}
+
+// EnqueueBatchIn mocks base method.
+func (m *MockEnqueuer) EnqueueBatchIn(arg0 string, arg1 int64, arg2 []map[string]interface{}) ([]*queue.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueBatchIn", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*queue.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueBatchIn indicates an expected call of EnqueueBatchIn.
+func (mr *MockEnqueuerMockRecorder) EnqueueBatchIn(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueBatchIn", reflect.TypeOf((*MockEnqueuer)(nil).EnqueueBatchIn), arg0, arg1, arg2)
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/app"
+	"swilly-delivery-service/internal/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func newRouter(dependency *app.Dependency) http.Handler {
+	suppressions := &suppressionHandler{list: dependency.Suppression}
+	files := &fileHandler{stats: dependency.Stats, directory: config.AppConfig.DirectoryPath}
+	validation := &validationHandler{dependency: dependency.DryRun(), maxUploadBytes: config.AppConfig.MaxUploadBytes}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/suppressions", suppressions.handle)
+	mux.HandleFunc("/suppressions/import", suppressions.importList)
+	mux.HandleFunc("/files/", files.handle)
+	mux.HandleFunc("/validate", validation.validate)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error("unable to write response", zap.Error(err))
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
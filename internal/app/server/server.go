@@ -2,79 +2,82 @@ package server
 
 import (
 	"context"
+	"errors"
+	"net"
 	"net/http"
-	"os"
-	"os/signal"
 	"swilly-delivery-service/config"
 	"swilly-delivery-service/internal/app"
 	"swilly-delivery-service/internal/pkg/log"
 	"swilly-delivery-service/internal/pkg/middleware"
-	"time"
+	"swilly-delivery-service/internal/pkg/spool"
 
-	"github.com/gocraft/work"
 	"go.uber.org/zap"
 )
 
+// Server serves the HTTP API and processes the files dropped in the directory.
 type Server struct {
-	httpServer *http.Server
+	httpServer    *http.Server
+	fileProcessor *FileProcessor
+	enqueuer      *spoolingEnqueuer
 }
 
-func StartServer() {
-	idleConsClosed := make(chan struct{})
-	server, err := newServer()
+func NewServer(dependency *app.Dependency) (*Server, error) {
+	enqueueConfig := config.AppConfig.EnqueueConfig
+	jobSpool, err := spool.NewFileSpool(enqueueConfig.SpoolPath)
 	if err != nil {
-		log.Fatal("unable to create the server", zap.Error(err))
+		return nil, err
 	}
+	enqueuer := newSpoolingEnqueuer(
+		dependency.Queue,
+		jobSpool,
+		enqueueConfig.Retries,
+		enqueueConfig.RetryBackoff,
+	)
 
-	go func() {
-		sigint := make(chan os.Signal, 1)
-		signal.Notify(sigint, os.Interrupt)
-		<-sigint
-
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		if err := server.stop(ctx); err != nil {
-			log.Error("server shutdown error", zap.Error(err))
-		}
-		idleConsClosed <- struct{}{}
-		close(idleConsClosed)
-	}()
-	ctx := context.Background()
-	_ = middleware.ProcessWithRecovery(ctx, func(ctx context.Context) error {
-		server.start(ctx)
-		return nil
-	})
+	fp, err := NewFileProcessor(config.AppConfig.DirectoryPath, enqueuer, dependency)
+	if err != nil {
+		return nil, err
+	}
 
-	<-idleConsClosed
+	s := &Server{
+		httpServer: &http.Server{
+			Addr:    ":" + config.AppConfig.HTTPServerPort,
+			Handler: newRouter(dependency),
+		},
+		fileProcessor: fp,
+		enqueuer:      enqueuer,
+	}
+	return s, nil
 }
 
-func (s *Server) start(ctx context.Context) {
-	fp, err := NewFileProcessor(config.AppConfig.DirectoryPath, work.NewEnqueuer("delivery", app.AppDependency.Redis))
+// Start starts processing files and serving the API in the background.
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
 	if err != nil {
-		log.Fatal("Error initializing file processor: %v", zap.Error(err))
+		return err
 	}
-	go fp.Start(ctx)
+	s.fileProcessor.Start(ctx)
+	go s.enqueuer.replaySpool(ctx, config.AppConfig.EnqueueConfig.ReplayInterval)
 
 	log.Info("starting app", zap.String("port", config.AppConfig.HTTPServerPort))
-	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Error("server start error", zap.Error(err))
-		panic(err)
-	}
+	go func() {
+		_ = middleware.ProcessWithRecovery(ctx, func(ctx context.Context) error {
+			if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+				log.Error("server error", zap.Error(err))
+			}
+			return nil
+		})
+	}()
+	return nil
 }
 
-func (s *Server) stop(ctx context.Context) error {
+// Stop stops picking up files and waits for the ones being processed to be checkpointed, then
+// shuts down the HTTP server, giving up on whatever is left when ctx is done. Jobs that can't be
+// enqueued meanwhile are spooled without waiting on retries.
+func (s *Server) Stop(ctx context.Context) error {
 	log.Info("stopping server")
-	return s.httpServer.Shutdown(ctx)
-}
-
-func newServer() (*Server, error) {
-	var err error
-	if err = app.Bootstrap(); err != nil {
-		return nil, err
-	}
-
-	s := &Server{
-		httpServer: &http.Server{},
-	}
-	return s, nil
+	s.enqueuer.stop()
+	fileErr := s.fileProcessor.Stop(ctx)
+	httpErr := s.httpServer.Shutdown(ctx)
+	return errors.Join(fileErr, httpErr)
 }
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"swilly-delivery-service/internal/pkg/suppression"
+)
+
+type suppressionRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+type suppressionResponse struct {
+	Updated int `json:"updated"`
+}
+
+type suppressionHandler struct {
+	list suppression.List
+}
+
+func (h *suppressionHandler) handle(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.add(w, r)
+	case http.MethodDelete:
+		h.remove(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+	}
+}
+
+// add godoc
+//
+//	@Summary Suppress user IDs
+//	@Description Adds user IDs to the global opt-out list. Suppressed users are never messaged. User IDs are normalized like those of files, and rejected if they don't follow the recipient ID rule.
+//	@Tags suppressions
+//	@Accept json
+//	@Produce json
+//	@Param request body suppressionRequest true "User IDs to suppress"
+//	@Success 200 {object} suppressionResponse
+//	@Failure 400 {object} errorResponse
+//	@Failure 500 {object} errorResponse
+//	@Router /suppressions [post]
+func (h *suppressionHandler) add(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, h.list.Add)
+}
+
+// remove godoc
+//
+//	@Summary Lift suppression for user IDs
+//	@Description Removes user IDs from the global opt-out list.
+//	@Tags suppressions
+//	@Accept json
+//	@Produce json
+//	@Param request body suppressionRequest true "User IDs to remove"
+//	@Success 200 {object} suppressionResponse
+//	@Failure 400 {object} errorResponse
+//	@Failure 500 {object} errorResponse
+//	@Router /suppressions [delete]
+func (h *suppressionHandler) remove(w http.ResponseWriter, r *http.Request) {
+	h.update(w, r, h.list.Remove)
+}
+
+func (h *suppressionHandler) update(w http.ResponseWriter, r *http.Request, apply func(...string) (int, error)) {
+	var request suppressionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(request.UserIDs) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("user_ids must not be empty"))
+		return
+	}
+
+	updated, err := apply(request.UserIDs...)
+	if err != nil {
+		writeError(w, updateErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, suppressionResponse{Updated: updated})
+}
+
+// importList godoc
+//
+//	@Summary Bulk import suppressed user IDs
+//	@Description Adds every user ID in the request body, one per line, to the global opt-out list. The import stops at the first invalid user ID.
+//	@Tags suppressions
+//	@Accept plain
+//	@Produce json
+//	@Param request body string true "Newline separated user IDs"
+//	@Success 200 {object} suppressionResponse
+//	@Failure 400 {object} errorResponse
+//	@Failure 500 {object} errorResponse
+//	@Router /suppressions/import [post]
+func (h *suppressionHandler) importList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	imported, err := h.list.Import(r.Body)
+	if err != nil {
+		writeError(w, updateErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, suppressionResponse{Updated: imported})
+}
+
+// updateErrorStatus returns the status of a response to an update of the list that failed with err.
+func updateErrorStatus(err error) int {
+	if errors.Is(err, suppression.ErrInvalidUserID) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
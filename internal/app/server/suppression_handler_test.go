@@ -0,0 +1,58 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"swilly-delivery-service/internal/pkg/suppression"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuppressionHandler_Add(t *testing.T) {
+	list := suppression.NewMockList(gomock.NewController(t))
+	list.EXPECT().Add("1", "2").Return(2, nil)
+	handler := &suppressionHandler{list: list}
+
+	recorder := httptest.NewRecorder()
+	handler.handle(recorder, httptest.NewRequest(http.MethodPost, "/suppressions", strings.NewReader(`{"user_ids":["1","2"]}`)))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"updated":2}`, recorder.Body.String())
+}
+
+func TestSuppressionHandler_RemoveEmpty(t *testing.T) {
+	handler := &suppressionHandler{list: suppression.NewMockList(gomock.NewController(t))}
+
+	recorder := httptest.NewRecorder()
+	handler.handle(recorder, httptest.NewRequest(http.MethodDelete, "/suppressions", strings.NewReader(`{"user_ids":[]}`)))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestSuppressionHandler_ImportError(t *testing.T) {
+	list := suppression.NewMockList(gomock.NewController(t))
+	list.EXPECT().Import(gomock.Any()).Return(0, errors.New("redis down"))
+	handler := &suppressionHandler{list: list}
+
+	recorder := httptest.NewRecorder()
+	handler.importList(recorder, httptest.NewRequest(http.MethodPost, "/suppressions/import", strings.NewReader("1\n2\n")))
+
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+}
+
+func TestSuppressionHandler_AddInvalid(t *testing.T) {
+	list := suppression.NewMockList(gomock.NewController(t))
+	list.EXPECT().Add("1", "abc").Return(0, fmt.Errorf("%w: \"abc\" is not an integer", suppression.ErrInvalidUserID))
+	handler := &suppressionHandler{list: list}
+
+	recorder := httptest.NewRecorder()
+	handler.handle(recorder, httptest.NewRequest(http.MethodPost, "/suppressions", strings.NewReader(`{"user_ids":["1","abc"]}`)))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"error":"invalid user ID: \"abc\" is not an integer"}`, recorder.Body.String())
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/app"
+	"swilly-delivery-service/internal/pkg/log"
+	"swilly-delivery-service/internal/pkg/queue"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxSampleJobs bounds how many of the jobs a dry run would enqueue are kept to be shown.
+const maxSampleJobs = 5
+
+// ValidationResult is the outcome of a dry run of a file.
+type ValidationResult struct {
+	Summary    fileSummary              `json:"summary"`
+	SampleJobs []map[string]interface{} `json:"sample_jobs"`
+}
+
+// recordingEnqueuer is the Enqueuer of dry runs. It keeps the first jobs instead of enqueueing them.
+type recordingEnqueuer struct {
+	// mutex guards jobs, recorded by the chunks of a file processed in parallel
+	mutex sync.Mutex
+	jobs  []map[string]interface{}
+}
+
+func (r *recordingEnqueuer) Enqueue(jobName string, args map[string]interface{}) (*queue.Job, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.jobs) < maxSampleJobs {
+		r.jobs = append(r.jobs, args)
+	}
+	return &queue.Job{Name: jobName, Args: args}, nil
+}
+
+func (r *recordingEnqueuer) EnqueueBatch(jobName string, args []map[string]interface{}) ([]*queue.Job, error) {
+	jobs := make([]*queue.Job, 0, len(args))
+	for _, jobArgs := range args {
+		job, _ := r.Enqueue(jobName, jobArgs)
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (r *recordingEnqueuer) EnqueueBatchIn(jobName string, secondsFromNow int64, args []map[string]interface{}) ([]*queue.Job, error) {
+	jobs, _ := r.EnqueueBatch(jobName, args)
+	runAt := time.Now().Unix() + secondsFromNow
+	for _, job := range jobs {
+		job.RunAt = runAt
+	}
+	return jobs, nil
+}
+
+// ValidateFile runs a file through the ingestion pipeline without enqueueing anything or moving the file.
+// The report the real run would write is written to report. dependency is expected to be a dry run one,
+// see app.Dependency.DryRun.
+func ValidateFile(ctx context.Context, path string, dependency *app.Dependency, report io.Writer) (*ValidationResult, error) {
+	recorder := &recordingEnqueuer{}
+	fp := &FileProcessor{
+		enqueuer:         recorder,
+		suppression:      dependency.Suppression,
+		dedup:            dependency.Dedup,
+		recipients:       dependency.Recipients,
+		segments:         dependency.Segments,
+		decrypter:        dependency.Decrypter,
+		signers:          dependency.Signers,
+		profiles:         dependency.Profiles,
+		manifestWait:     config.AppConfig.ManifestConfig.Wait,
+		manifestRequired: config.AppConfig.ManifestConfig.Required,
+		lookupBatchSize:  config.AppConfig.EnrichmentConfig.BatchSize,
+		inactivePolicy:   config.AppConfig.EnrichmentConfig.InactivePolicy,
+		checksums:        dependency.Checksums,
+		thresholds:       newFileThresholds(),
+		batchSize:        config.AppConfig.EnqueueConfig.BatchSize,
+		flushInterval:    config.AppConfig.EnqueueConfig.FlushInterval,
+		chunkSize:        config.AppConfig.FileChunkConfig.Size,
+		chunkWorkers:     config.AppConfig.FileChunkConfig.Workers,
+	}
+
+	run := newFileRun(path)
+	if err := run.startReport(report); err != nil {
+		return nil, err
+	}
+	run.summary.Status, run.summary.Reason = fp.loadManifest(run)
+	run.summary.Manifest = run.manifest
+	if isSegmentFile(run.filename) && run.summary.Status == "" {
+		dir, err := os.MkdirTemp("", "segments")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		run.summary.Status, run.summary.Reason = fp.expandRun(run, membersPath(filepath.Join(dir, run.filename)))
+	}
+	if run.summary.Status == "" {
+		run.summary.Status, run.summary.Reason = fp.ingestFile(ctx, run)
+	}
+	run.summary.FinishedAt = time.Now()
+	run.summary.SignedBy = run.signer
+	if err := run.closeReport(); err != nil {
+		return nil, err
+	}
+	if err := fp.dedup.Release(run.id); err != nil {
+		log.Error("unable to release dedup state", zap.String("filename", run.filename), zap.Error(err))
+	}
+
+	return &ValidationResult{Summary: run.summary, SampleJobs: recorder.jobs}, nil
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"swilly-delivery-service/internal/app"
+	"swilly-delivery-service/internal/pkg/manifest"
+)
+
+type validationResponse struct {
+	*ValidationResult
+	Report string `json:"report"`
+}
+
+type validationHandler struct {
+	dependency *app.Dependency
+	// maxUploadBytes bounds the size of the uploaded file
+	maxUploadBytes int64
+}
+
+// errInvalidUpload is returned for requests to /validate whose upload can't be made sense of.
+var errInvalidUpload = errors.New("invalid upload")
+
+// validate godoc
+//
+//	@Summary Validate a file without sending anything
+//	@Description Runs the uploaded file through the ingestion pipeline as a dry run and returns the counts of valid, invalid, duplicate and suppressed recipients, sample jobs and the report the real run would write. The file is either the request body, its sidecars being passed as parameters, or the `file` part of a multipart/form-data request, its sidecars being the `manifest`, `signature` and `manifest_signature` parts. A manifest starting with `{` is read as JSON, as YAML otherwise.
+//	@Tags files
+//	@Accept plain
+//	@Accept mpfd
+//	@Produce json
+//	@Param name query string true "File name, as it would be dropped in the directory"
+//	@Param manifest query string false "Manifest of the file, as in its .manifest.json or .manifest.yaml file"
+//	@Param signature query string false "Detached signature of the file, as in its .sig file, when files must be signed"
+//	@Param manifest_signature query string false "Detached signature of the manifest, when files must be signed"
+//	@Param request body string true "File content"
+//	@Success 200 {object} validationResponse
+//	@Failure 400 {object} errorResponse
+//	@Failure 413 {object} errorResponse
+//	@Failure 500 {object} errorResponse
+//	@Router /validate [post]
+func (h *validationHandler) validate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	name := filepath.Base(r.URL.Query().Get("name"))
+	if name == "." || name == string(filepath.Separator) {
+		writeError(w, http.StatusBadRequest, errors.New("name is required"))
+		return
+	}
+
+	// The file is read twice, so it has to be seekable
+	dir, err := os.MkdirTemp("", "validate")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, name)
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadBytes)
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		err = writeMultipartUpload(path, r)
+	} else {
+		err = writeUpload(path, r.Body, r.URL.Query())
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("file is larger than %d bytes", tooLarge.Limit))
+		case errors.Is(err, errInvalidUpload):
+			writeError(w, http.StatusBadRequest, err)
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	var report bytes.Buffer
+	result, err := ValidateFile(r.Context(), path, h.dependency, &report)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, validationResponse{ValidationResult: result, Report: report.String()})
+}
+
+// uploadSidecars are the files uploaded along with the file to validate, written next to it the way they
+// would be in the directory.
+type uploadSidecars struct {
+	manifest          []byte
+	signature         []byte
+	manifestSignature []byte
+}
+
+// writeUpload writes body, the file to validate, to path along with the sidecars passed as parameters.
+func writeUpload(path string, body io.Reader, params url.Values) error {
+	if err := writeFile(path, body); err != nil {
+		return err
+	}
+	return uploadSidecars{
+		manifest:          []byte(params.Get("manifest")),
+		signature:         []byte(params.Get("signature")),
+		manifestSignature: []byte(params.Get("manifest_signature")),
+	}.write(path)
+}
+
+// writeMultipartUpload writes the file part of r, the file to validate, to path along with the sidecars
+// uploaded as the other parts.
+func writeMultipartUpload(path string, r *http.Request) error {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidUpload, err)
+	}
+
+	var sidecars uploadSidecars
+	hasFile := false
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return err
+			}
+			return fmt.Errorf("%w: %v", errInvalidUpload, err)
+		}
+
+		switch part.FormName() {
+		case "file":
+			err = writeFile(path, part)
+			hasFile = true
+		case "manifest":
+			sidecars.manifest, err = io.ReadAll(part)
+		case "signature":
+			sidecars.signature, err = io.ReadAll(part)
+		case "manifest_signature":
+			sidecars.manifestSignature, err = io.ReadAll(part)
+		default:
+			err = fmt.Errorf("%w: unexpected part %q", errInvalidUpload, part.FormName())
+		}
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if !hasFile {
+		return fmt.Errorf("%w: file part is required", errInvalidUpload)
+	}
+	return sidecars.write(path)
+}
+
+// write writes the sidecars next to the file at path.
+func (s uploadSidecars) write(path string) error {
+	if len(s.signature) > 0 {
+		if err := os.WriteFile(signaturePath(path), s.signature, 0600); err != nil {
+			return err
+		}
+	}
+	if len(s.manifest) == 0 {
+		if len(s.manifestSignature) > 0 {
+			return fmt.Errorf("%w: manifest_signature without a manifest", errInvalidUpload)
+		}
+		return nil
+	}
+
+	manifestPath := path + manifest.YAMLSuffix
+	if bytes.HasPrefix(bytes.TrimSpace(s.manifest), []byte("{")) {
+		manifestPath = path + manifest.JSONSuffix
+	}
+	if err := os.WriteFile(manifestPath, s.manifest, 0600); err != nil {
+		return err
+	}
+	if len(s.manifestSignature) > 0 {
+		return os.WriteFile(signaturePath(manifestPath), s.manifestSignature, 0600)
+	}
+	return nil
+}
+
+func writeFile(path string, content io.Reader) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
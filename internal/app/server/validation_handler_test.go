@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"swilly-delivery-service/config"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationHandler_RejectsLargeUploads(t *testing.T) {
+	handler := &validationHandler{maxUploadBytes: 4}
+
+	recorder := httptest.NewRecorder()
+	handler.validate(recorder, httptest.NewRequest(http.MethodPost, "/validate?name=swilly_diwali", strings.NewReader("123\n456\n")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.JSONEq(t, `{"error": "file is larger than 4 bytes"}`, recorder.Body.String())
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	assert.NoError(t, form.WriteField("file", "123\n456\n"))
+	assert.NoError(t, form.Close())
+	request := httptest.NewRequest(http.MethodPost, "/validate?name=swilly_diwali", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	recorder = httptest.NewRecorder()
+	handler.validate(recorder, request)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+}
+
+func TestValidationHandler_RejectsUploadsWithoutFile(t *testing.T) {
+	handler := &validationHandler{maxUploadBytes: 1 << 20}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	assert.NoError(t, form.WriteField("manifest", diwaliManifest))
+	assert.NoError(t, form.Close())
+
+	request := httptest.NewRequest(http.MethodPost, "/validate?name=swilly_diwali", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	handler.validate(recorder, request)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.JSONEq(t, `{"error": "invalid upload: file part is required"}`, recorder.Body.String())
+}
+
+func (f *FileProcessSuite) TestValidationHandler_ChecksUploadedManifest() {
+	handler := &validationHandler{dependency: f.dependency, maxUploadBytes: 1 << 20}
+	// The manifest expects 3 rows
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	f.NoError(form.WriteField("file", "1\n2\n3\n4\n5\n"))
+	f.NoError(form.WriteField("manifest", diwaliManifest))
+	f.NoError(form.Close())
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_diwali").Return(true, "", nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+
+	request := httptest.NewRequest(http.MethodPost, "/validate?name=swilly_diwali", &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	handler.validate(recorder, request)
+
+	f.Equal(http.StatusOK, recorder.Code)
+	summary := f.validationSummary(recorder)
+	f.Equal(quarantineFolder, summary.Status)
+	f.Equal("5 rows, expected 3 within 0.00%", summary.Reason)
+	f.Equal("diwali_sale", summary.Manifest.Campaign)
+}
+
+func (f *FileProcessSuite) TestValidationHandler_ChecksManifestParameter() {
+	handler := &validationHandler{dependency: f.dependency, maxUploadBytes: 1 << 20}
+	expired := "campaign: diwali_sale\nowner: growth@swilly.in\ntemplate: Hi\nchannel: sms\nexpected_rows: 1\n" +
+		"expires_at: 2020-01-01T00:00:00Z\n"
+	f.checksums.EXPECT().Claim(gomock.Any(), "swilly_diwali").Return(true, "", nil)
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+
+	target := "/validate?name=swilly_diwali&manifest=" + url.QueryEscape(expired)
+	recorder := httptest.NewRecorder()
+	handler.validate(recorder, httptest.NewRequest(http.MethodPost, target, strings.NewReader("1\n")))
+
+	f.Equal(http.StatusOK, recorder.Code)
+	summary := f.validationSummary(recorder)
+	f.Equal(quarantineFolder, summary.Status)
+	f.Equal("campaign expired at 2020-01-01T00:00:00Z", summary.Reason)
+}
+
+func (f *FileProcessSuite) TestValidationHandler_RequiresManifest() {
+	config.AppConfig.ManifestConfig.Required = true
+	defer func() { config.AppConfig.ManifestConfig.Required = false }()
+	handler := &validationHandler{dependency: f.dependency, maxUploadBytes: 1 << 20}
+	f.dedup.EXPECT().Release(gomock.Any()).Return(nil)
+
+	recorder := httptest.NewRecorder()
+	handler.validate(recorder, httptest.NewRequest(http.MethodPost, "/validate?name=swilly_diwali", strings.NewReader("1\n")))
+
+	f.Equal(http.StatusOK, recorder.Code)
+	summary := f.validationSummary(recorder)
+	f.Equal(failedFolder, summary.Status)
+	f.Equal("no manifest received within 0s", summary.Reason)
+}
+
+func (f *FileProcessSuite) validationSummary(recorder *httptest.ResponseRecorder) fileSummary {
+	var response validationResponse
+	f.NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	return response.Summary
+}
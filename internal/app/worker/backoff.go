@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"math/rand"
+	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/pkg/queue"
+	"time"
+)
+
+// exponentialBackoff waits base after the first fail, doubling with every fail up to max. Half of the
+// wait is randomized so that jobs failing together, e.g. while the webhook was down, don't retry together.
+func exponentialBackoff(base, max time.Duration) func(job *queue.Job) int64 {
+	return func(job *queue.Job) int64 {
+		delay := max
+		if job.Fails > 0 && job.Fails < 63 && base < max>>(job.Fails-1) {
+			delay = base << (job.Fails - 1)
+		}
+		seconds := int64(delay / time.Second)
+		if seconds < 2 {
+			return seconds
+		}
+		return seconds/2 + rand.Int63n(seconds/2+1)
+	}
+}
+
+// jobOptions builds the queue options of a job from its config.
+func jobOptions(jobName string) queue.JobOptions {
+	job := config.AppConfig.WorkerPoolConfig.Jobs[jobName]
+	options := queue.JobOptions{
+		Priority:       uint(job.Priority),
+		MaxFails:       uint(job.MaxFails),
+		MaxConcurrency: uint(job.MaxConcurrency),
+	}
+	if job.Backoff == config.BackoffExponential {
+		options.Backoff = exponentialBackoff(job.BackoffBase, job.BackoffMax)
+	}
+	return options
+}
@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"swilly-delivery-service/internal/pkg/queue"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := exponentialBackoff(10*time.Second, 5*time.Minute)
+
+	tests := []struct {
+		fails    int64
+		min, max int64
+	}{
+		{fails: 1, min: 5, max: 10},
+		{fails: 2, min: 10, max: 20},
+		{fails: 4, min: 40, max: 80},
+		// Capped from the sixth fail on, without overflowing for huge fail counts
+		{fails: 6, min: 150, max: 300},
+		{fails: 100, min: 150, max: 300},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			delay := backoff(&queue.Job{Fails: tt.fails})
+			assert.GreaterOrEqual(t, delay, tt.min, "fails %d", tt.fails)
+			assert.LessOrEqual(t, delay, tt.max, "fails %d", tt.fails)
+		}
+	}
+}
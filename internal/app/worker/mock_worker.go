@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: swilly-delivery-service/internal/app/worker (interfaces: Enqueuer)
+
+// Package worker is a generated GoMock package.
+package worker
+
+import (
+	reflect "reflect"
+	queue "swilly-delivery-service/internal/pkg/queue"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockEnqueuer is a mock of Enqueuer interface.
+type MockEnqueuer struct {
+	ctrl     *gomock.Controller
+	recorder *MockEnqueuerMockRecorder
+}
+
+// MockEnqueuerMockRecorder is the mock recorder for MockEnqueuer.
+type MockEnqueuerMockRecorder struct {
+	mock *MockEnqueuer
+}
+
+// NewMockEnqueuer creates a new mock instance.
+func NewMockEnqueuer(ctrl *gomock.Controller) *MockEnqueuer {
+	mock := &MockEnqueuer{ctrl: ctrl}
+	mock.recorder = &MockEnqueuerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockEnqueuer) EXPECT() *MockEnqueuerMockRecorder {
+	return m.recorder
+}
+
+// EnqueueIn mocks base method.
+func (m *MockEnqueuer) EnqueueIn(arg0 string, arg1 int64, arg2 map[string]interface{}) (*queue.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueIn", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*queue.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueIn indicates an expected call of EnqueueIn.
+func (mr *MockEnqueuerMockRecorder) EnqueueIn(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueIn", reflect.TypeOf((*MockEnqueuer)(nil).EnqueueIn), arg0, arg1, arg2)
+}
@@ -2,46 +2,173 @@ package worker
 
 import (
 	"context"
-	"os"
-	"os/signal"
+	"fmt"
+	"math"
 	"swilly-delivery-service/config"
 	"swilly-delivery-service/internal/app"
+	"swilly-delivery-service/internal/pkg/frequency"
 	"swilly-delivery-service/internal/pkg/log"
+	"swilly-delivery-service/internal/pkg/queue"
+	"swilly-delivery-service/internal/pkg/stats"
+	"swilly-delivery-service/internal/pkg/suppression"
+	"time"
 
-	"github.com/gocraft/work"
 	"go.uber.org/zap"
 )
 
-func StartWorker(ctx context.Context) error {
-	if err := app.Bootstrap(); err != nil {
-		return err
+// Enqueuer schedules jobs to be run later, e.g. when delivery is deferred by the frequency cap.
+type Enqueuer interface {
+	EnqueueIn(jobName string, secondsFromNow int64, args map[string]interface{}) (*queue.Job, error)
+}
+
+type alertHandler struct {
+	suppression suppression.List
+	stats       stats.Store
+	frequency   frequency.Limiter
+	enqueuer    Enqueuer
+	capPolicy   string
+}
+
+// Worker delivers the messages of the jobs enqueued by the server.
+type Worker struct {
+	queue        queue.Backend
+	drainTimeout time.Duration
+}
+
+func NewWorker(dependency *app.Dependency) *Worker {
+	handler := &alertHandler{
+		suppression: dependency.Suppression,
+		stats:       dependency.Stats,
+		frequency:   dependency.Frequency,
+		enqueuer:    dependency.Queue,
+		capPolicy:   config.AppConfig.FrequencyCapConfig.Policy,
 	}
+	dependency.Queue.Register(config.AppConfig.JobName, jobOptions(config.AppConfig.JobName), handler.triggerAlert)
 
-	pool := work.NewWorkerPool(ctx, 10, "delivery", app.AppDependency.Redis)
-	pool.JobWithOptions(config.AppConfig.JobName, work.JobOptions{
-		MaxFails: 3,
-		SkipDead: false,
-	}, triggerAlert)
-	pool.Start()
+	return &Worker{queue: dependency.Queue, drainTimeout: config.AppConfig.WorkerPoolConfig.DrainTimeout}
+}
 
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, os.Kill)
-	<-signalChan
-	pool.Stop()
+// Start starts fetching and running jobs in the background.
+func (w *Worker) Start(context.Context) error {
+	log.Info("starting worker")
+	w.queue.Start()
+	return nil
+}
 
+// Stop stops fetching new jobs and waits for the running ones to finish for the drain timeout, or until
+// ctx is done. Jobs still running then are logged, and requeued with redis.
+func (w *Worker) Stop(ctx context.Context) error {
+	log.Info("stopping worker", zap.Duration("drainTimeout", w.drainTimeout))
+	ctx, cancel := context.WithTimeout(ctx, w.drainTimeout)
+	defer cancel()
+	w.queue.Drain(ctx)
 	return nil
 }
 
-func triggerAlert(job *work.Job) error {
+func (h *alertHandler) triggerAlert(job *queue.Job) error {
 	// Extract arguments from the job
 	userID := job.ArgString("userID")
 	message := job.ArgString("message")
 	if err := job.ArgError(); err != nil {
 		return err
 	}
+	filename, _ := job.Args["filename"].(string)
+	campaign, _ := job.Args["campaign"].(string)
+
+	// The manifest of the campaign may say when to send it and until when it's worth sending
+	sendAt, expiresAt, err := campaignSchedule(job)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !expiresAt.IsZero() && !now.Before(expiresAt) {
+		log.Info("Dropping job of expired campaign", zap.String("userID", userID), zap.String("campaign", campaign))
+		h.recordOutcome(stats.CampaignScope(campaign), "expired_dropped")
+		return nil
+	}
+	if now.Before(sendAt) {
+		return h.deferUntilSendAt(job, campaign, sendAt.Sub(now))
+	}
+
+	// The user may have opted out after the job was enqueued
+	suppressed, err := h.suppression.Contains(userID)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		log.Info("Skipping delivery to suppressed user", zap.String("userID", userID), zap.String("filename", filename))
+		if filename != "" {
+			h.recordOutcome(stats.FileScope(filename), "suppressed_at_delivery")
+		}
+		return nil
+	}
+
+	decision, err := h.frequency.Allow(userID)
+	if err != nil {
+		return err
+	}
+	if !decision.Allowed {
+		return h.handleCapped(job, campaign, decision)
+	}
 
 	log.Info("Job Arguments", zap.String("userID", userID), zap.String("message", message))
 	// Make HTTP call to webhook api. More info in documentation
 
+	h.recordOutcome(stats.CampaignScope(campaign), "delivered")
 	return nil
 }
+
+// handleCapped drops or defers a job for a user who reached the frequency cap.
+func (h *alertHandler) handleCapped(job *queue.Job, campaign string, decision frequency.Decision) error {
+	userID := job.ArgString("userID")
+	if h.capPolicy != config.FrequencyCapPolicyDefer {
+		log.Info("Dropping job for frequency capped user", zap.String("userID", userID), zap.String("campaign", campaign))
+		h.recordOutcome(stats.CampaignScope(campaign), "capped_dropped")
+		return nil
+	}
+
+	delay := int64(math.Ceil(decision.RetryAfter.Seconds()))
+	if _, err := h.enqueuer.EnqueueIn(job.Name, delay, job.Args); err != nil {
+		log.Error("unable to defer frequency capped job", zap.String("userID", userID), zap.Error(err))
+		return err
+	}
+	log.Info("Deferring job for frequency capped user", zap.String("userID", userID), zap.String("campaign", campaign), zap.Int64("delaySeconds", delay))
+	h.recordOutcome(stats.CampaignScope(campaign), "capped_deferred")
+	return nil
+}
+
+// deferUntilSendAt schedules a job picked up before the send_at of its campaign to be run then. Ingestion
+// schedules the jobs of such campaigns already, those it enqueued anyway (e.g. spooled ones) end up here.
+func (h *alertHandler) deferUntilSendAt(job *queue.Job, campaign string, wait time.Duration) error {
+	userID := job.ArgString("userID")
+	delay := int64(math.Ceil(wait.Seconds()))
+	if _, err := h.enqueuer.EnqueueIn(job.Name, delay, job.Args); err != nil {
+		log.Error("unable to schedule job of campaign", zap.String("userID", userID), zap.Error(err))
+		return err
+	}
+	log.Info("Scheduling job until campaign send_at", zap.String("userID", userID), zap.String("campaign", campaign), zap.Int64("delaySeconds", delay))
+	return nil
+}
+
+// campaignSchedule returns the send_at and expires_at arguments of a job, zero when missing.
+func campaignSchedule(job *queue.Job) (time.Time, time.Time, error) {
+	var times [2]time.Time
+	for i, key := range []string{"send_at", "expires_at"} {
+		value, ok := job.Args[key].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("job argument %s is not a RFC 3339 time: %w", key, err)
+		}
+		times[i] = t
+	}
+	return times[0], times[1], nil
+}
+
+func (h *alertHandler) recordOutcome(scope, outcome string) {
+	if err := h.stats.Incr(scope, outcome, 1); err != nil {
+		log.Error("unable to record delivery outcome", zap.String("scope", scope), zap.String("outcome", outcome), zap.Error(err))
+	}
+}
@@ -0,0 +1,139 @@
+package worker
+
+import (
+	"context"
+	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/pkg/frequency"
+	"swilly-delivery-service/internal/pkg/queue"
+	"swilly-delivery-service/internal/pkg/stats"
+	"swilly-delivery-service/internal/pkg/suppression"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type WorkerSuite struct {
+	suite.Suite
+	suppression *suppression.MockList
+	stats       *stats.MockStore
+	frequency   *frequency.MockLimiter
+	enqueuer    *MockEnqueuer
+	handler     *alertHandler
+	job         *queue.Job
+}
+
+func (w *WorkerSuite) SetupTest() {
+	controller := gomock.NewController(w.T())
+	w.suppression = suppression.NewMockList(controller)
+	w.stats = stats.NewMockStore(controller)
+	w.frequency = frequency.NewMockLimiter(controller)
+	w.enqueuer = NewMockEnqueuer(controller)
+	w.handler = &alertHandler{
+		suppression: w.suppression,
+		stats:       w.stats,
+		frequency:   w.frequency,
+		enqueuer:    w.enqueuer,
+		capPolicy:   config.FrequencyCapPolicyDrop,
+	}
+	w.job = &queue.Job{
+		Name: "send_message",
+		Args: map[string]interface{}{"userID": "42", "message": "message", "filename": "swilly_file", "campaign": "swilly_file"},
+	}
+}
+
+func TestWorker(t *testing.T) {
+	suite.Run(t, new(WorkerSuite))
+}
+
+func (w *WorkerSuite) TestTriggerAlert_Delivers() {
+	w.suppression.EXPECT().Contains("42").Return(false, nil)
+	w.frequency.EXPECT().Allow("42").Return(frequency.Decision{Allowed: true}, nil)
+	w.stats.EXPECT().Incr(stats.CampaignScope("swilly_file"), "delivered", int64(1)).Return(nil)
+
+	w.NoError(w.handler.triggerAlert(w.job))
+}
+
+func (w *WorkerSuite) TestTriggerAlert_SkipsSuppressedUser() {
+	w.suppression.EXPECT().Contains("42").Return(true, nil)
+	w.stats.EXPECT().Incr(stats.FileScope("swilly_file"), "suppressed_at_delivery", int64(1)).Return(nil)
+
+	w.NoError(w.handler.triggerAlert(w.job))
+}
+
+func (w *WorkerSuite) TestTriggerAlert_DropsCappedJob() {
+	w.suppression.EXPECT().Contains("42").Return(false, nil)
+	w.frequency.EXPECT().Allow("42").Return(frequency.Decision{RetryAfter: time.Minute}, nil)
+	w.stats.EXPECT().Incr(stats.CampaignScope("swilly_file"), "capped_dropped", int64(1)).Return(nil)
+
+	w.NoError(w.handler.triggerAlert(w.job))
+}
+
+func (w *WorkerSuite) TestTriggerAlert_DefersCappedJob() {
+	w.handler.capPolicy = config.FrequencyCapPolicyDefer
+	w.suppression.EXPECT().Contains("42").Return(false, nil)
+	w.frequency.EXPECT().Allow("42").Return(frequency.Decision{RetryAfter: 1500 * time.Millisecond}, nil)
+	w.enqueuer.EXPECT().EnqueueIn("send_message", int64(2), w.job.Args).Return(nil, nil)
+	w.stats.EXPECT().Incr(stats.CampaignScope("swilly_file"), "capped_deferred", int64(1)).Return(nil)
+
+	w.NoError(w.handler.triggerAlert(w.job))
+}
+
+func (w *WorkerSuite) TestTriggerAlert_DropsExpiredJob() {
+	w.job.Args["expires_at"] = time.Now().Add(-time.Minute).Format(time.RFC3339)
+	w.stats.EXPECT().Incr(stats.CampaignScope("swilly_file"), "expired_dropped", int64(1)).Return(nil)
+
+	w.NoError(w.handler.triggerAlert(w.job))
+}
+
+func (w *WorkerSuite) TestTriggerAlert_DefersJobUntilSendAt() {
+	w.job.Args["send_at"] = time.Now().Add(time.Hour).Format(time.RFC3339)
+	w.job.Args["expires_at"] = time.Now().Add(2 * time.Hour).Format(time.RFC3339)
+	w.enqueuer.EXPECT().EnqueueIn("send_message", gomock.Any(), w.job.Args).DoAndReturn(
+		func(_ string, delay int64, _ map[string]interface{}) (*queue.Job, error) {
+			w.True(delay > 3590 && delay <= 3600, delay)
+			return nil, nil
+		})
+
+	w.NoError(w.handler.triggerAlert(w.job))
+}
+
+func (w *WorkerSuite) TestTriggerAlert_DeliversJobAfterSendAt() {
+	w.job.Args["send_at"] = time.Now().Add(-time.Minute).Format(time.RFC3339)
+	w.job.Args["expires_at"] = time.Now().Add(time.Hour).Format(time.RFC3339)
+	w.suppression.EXPECT().Contains("42").Return(false, nil)
+	w.frequency.EXPECT().Allow("42").Return(frequency.Decision{Allowed: true}, nil)
+	w.stats.EXPECT().Incr(stats.CampaignScope("swilly_file"), "delivered", int64(1)).Return(nil)
+
+	w.NoError(w.handler.triggerAlert(w.job))
+}
+
+func (w *WorkerSuite) TestTriggerAlert_RejectsInvalidSendAt() {
+	w.job.Args["send_at"] = "tomorrow"
+
+	w.Error(w.handler.triggerAlert(w.job))
+}
+
+func TestWorker_StopGivesUpAfterDrainTimeout(t *testing.T) {
+	backend := queue.NewMemoryBackend(1)
+	running, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	backend.Register("send_message", queue.JobOptions{MaxFails: 1}, func(*queue.Job) error {
+		close(running)
+		<-release
+		return nil
+	})
+	worker := &Worker{queue: backend, drainTimeout: 20 * time.Millisecond}
+	require.NoError(t, worker.Start(context.Background()))
+	_, err := backend.Enqueue("send_message", map[string]interface{}{"userID": "42"})
+	require.NoError(t, err)
+	<-running
+
+	// Shorter than the shutdown timeout, the drain doesn't hold the shutdown of the rest of the process
+	start := time.Now()
+	require.NoError(t, worker.Stop(context.Background()))
+	assert.True(t, time.Since(start) < time.Second)
+}
@@ -0,0 +1,131 @@
+package checksum
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const keyPrefix = "checksum:"
+
+// Store remembers the checksums of processed files.
+type Store interface {
+	// Claim records checksum for filename. If the checksum was already recorded it returns false
+	// along with the name of the file it was first recorded for.
+	Claim(checksum, filename string) (bool, string, error)
+	// Release forgets checksum so that the same content can be processed again.
+	Release(checksum string) error
+}
+
+type redisStore struct {
+	pool   *redis.Pool
+	prefix string
+	ttl    time.Duration
+	dryRun bool
+}
+
+// NewRedisStore returns a Store that forgets checksums after ttl.
+func NewRedisStore(pool *redis.Pool, prefix string, ttl time.Duration) Store {
+	return &redisStore{pool: pool, prefix: prefix, ttl: ttl}
+}
+
+// NewDryRunStore returns a Store that looks up checksums recorded by a redis Store but never records
+// or forgets any, so that validating a file does not affect later runs.
+func NewDryRunStore(pool *redis.Pool, prefix string) Store {
+	return &redisStore{pool: pool, prefix: prefix, dryRun: true}
+}
+
+func (s *redisStore) Claim(checksum, filename string) (bool, string, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	if s.dryRun {
+		previous, err := redis.String(conn.Do("GET", s.prefix+keyPrefix+checksum))
+		if errors.Is(err, redis.ErrNil) {
+			return true, "", nil
+		}
+		return false, previous, err
+	}
+
+	_, err := redis.String(conn.Do("SET", s.prefix+keyPrefix+checksum, filename, "NX", "PX", s.ttl.Milliseconds()))
+	if err == nil {
+		return true, "", nil
+	}
+	if !errors.Is(err, redis.ErrNil) {
+		return false, "", err
+	}
+
+	previous, err := redis.String(conn.Do("GET", s.prefix+keyPrefix+checksum))
+	if errors.Is(err, redis.ErrNil) {
+		// The record expired in between, try again
+		return s.Claim(checksum, filename)
+	}
+	return false, previous, err
+}
+
+func (s *redisStore) Release(checksum string) error {
+	if s.dryRun {
+		return nil
+	}
+
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", s.prefix+keyPrefix+checksum)
+	return err
+}
+
+// MemoryStore is a Store kept in memory, for running without redis. It's lost when the process exits.
+type MemoryStore struct {
+	ttl    time.Duration
+	now    func() time.Time
+	dryRun bool
+
+	mutex *sync.Mutex
+	files map[string]memoryClaim
+}
+
+type memoryClaim struct {
+	filename string
+	expires  time.Time
+}
+
+// NewMemoryStore returns a MemoryStore that forgets checksums after ttl.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, now: time.Now, mutex: &sync.Mutex{}, files: make(map[string]memoryClaim)}
+}
+
+// DryRun returns a Store that looks up the checksums recorded by s but never records or forgets any.
+func (s *MemoryStore) DryRun() Store {
+	dryRun := *s
+	dryRun.dryRun = true
+	return &dryRun
+}
+
+func (s *MemoryStore) Claim(checksum, filename string) (bool, string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	if claim, ok := s.files[checksum]; ok && now.Before(claim.expires) {
+		return false, claim.filename, nil
+	}
+	if !s.dryRun {
+		s.files[checksum] = memoryClaim{filename: filename, expires: now.Add(s.ttl)}
+	}
+	return true, "", nil
+}
+
+func (s *MemoryStore) Release(checksum string) error {
+	if s.dryRun {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.files, checksum)
+	return nil
+}
@@ -0,0 +1,116 @@
+package checksum
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStore_Claim(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := NewRedisStore(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}, "delivery:", time.Hour)
+
+	claimed, previous, err := store.Claim("abc", "swilly_file_0")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Empty(t, previous)
+
+	claimed, previous, err = store.Claim("abc", "swilly_file_1")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, "swilly_file_0", previous)
+
+	server.FastForward(2 * time.Hour)
+
+	claimed, _, err = store.Claim("abc", "swilly_file_1")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestRedisStore_Release(t *testing.T) {
+	server := miniredis.RunT(t)
+	store := NewRedisStore(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}, "delivery:", time.Hour)
+
+	_, _, err := store.Claim("abc", "swilly_file_0")
+	require.NoError(t, err)
+	require.NoError(t, store.Release("abc"))
+
+	claimed, _, err := store.Claim("abc", "swilly_file_1")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func TestDryRunStore_Claim(t *testing.T) {
+	server := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+	dryRun := NewDryRunStore(pool, "delivery:")
+
+	claimed, _, err := dryRun.Claim("abc", "swilly_file_0")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	assert.False(t, server.Exists("delivery:"+keyPrefix+"abc"))
+
+	_, _, err = NewRedisStore(pool, "delivery:", time.Hour).Claim("abc", "swilly_file_0")
+	require.NoError(t, err)
+
+	claimed, previous, err := dryRun.Claim("abc", "swilly_file_1")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, "swilly_file_0", previous)
+
+	require.NoError(t, dryRun.Release("abc"))
+	assert.True(t, server.Exists("delivery:"+keyPrefix+"abc"))
+}
+
+func TestMemoryStore_Claim(t *testing.T) {
+	store := NewMemoryStore(time.Hour)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+	dryRun := store.DryRun()
+
+	claimed, _, err := dryRun.Claim("abc", "swilly_file_0")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, _, err = store.Claim("abc", "swilly_file_0")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	for _, s := range []Store{store, dryRun} {
+		claimed, previous, err := s.Claim("abc", "swilly_file_1")
+		require.NoError(t, err)
+		assert.False(t, claimed)
+		assert.Equal(t, "swilly_file_0", previous)
+	}
+
+	require.NoError(t, dryRun.Release("abc"))
+	claimed, _, err = store.Claim("abc", "swilly_file_1")
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	require.NoError(t, store.Release("abc"))
+	claimed, _, err = store.Claim("abc", "swilly_file_1")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	now = now.Add(2 * time.Hour)
+	claimed, _, err = store.Claim("abc", "swilly_file_2")
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
@@ -0,0 +1,291 @@
+package dedup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	fileKeyPrefix     = "dedup:file:"
+	campaignKeyPrefix = "dedup:campaign:"
+
+	// fileTTL bounds how long the state of a file run survives if it is never released,
+	// e.g. when the server dies while processing it.
+	fileTTL = 24 * time.Hour
+)
+
+// Result tells whether a user ID was seen before and where.
+type Result int
+
+const (
+	Unique Result = iota
+	DuplicateInFile
+	DuplicateInCampaign
+)
+
+// checkScript marks user IDs as seen for a file run (a set) and for its campaign (a sorted set
+// scored by the time the user was last enqueued), and returns which of them already had each.
+// On dry runs the campaign is only looked at, and on resends it's not looked at. Users enqueued
+// before the window are trimmed from the campaign as it's written to, so that the set of a
+// campaign kept alive by new files doesn't grow forever.
+var checkScript = redis.NewScript(2, `
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local results = {}
+local recorded = false
+for i = 6, #ARGV do
+	local result = 0
+	if redis.call('SADD', KEYS[1], ARGV[i]) == 0 then
+		result = 1
+	elseif window > 0 then
+		local last = redis.call('ZSCORE', KEYS[2], ARGV[i])
+		if last and tonumber(last) > now - window and ARGV[5] ~= '1' then
+			result = 2
+		elseif ARGV[4] ~= '1' then
+			if not recorded then
+				redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', now - window)
+				recorded = true
+			end
+			redis.call('ZADD', KEYS[2], now, ARGV[i])
+		end
+	end
+	results[#results + 1] = result
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+if recorded then
+	redis.call('PEXPIRE', KEYS[2], window)
+end
+return results
+`)
+
+// Tracker detects user IDs repeated within a file or across files of the same campaign.
+type Tracker interface {
+	Check(run, campaign, userID string) (Result, error)
+	// CheckResend is Check for files explicitly sent again, whose users are only deduplicated within the file.
+	CheckResend(run, campaign, userID string) (Result, error)
+	// CheckMany is Check for several users at once, in one round trip. The results are in the order of userIDs.
+	CheckMany(run, campaign string, userIDs []string) ([]Result, error)
+	// CheckResendMany is CheckResend for several users at once, in one round trip.
+	CheckResendMany(run, campaign string, userIDs []string) ([]Result, error)
+	// Forget drops users Check marked for a campaign whose jobs could not be enqueued after all, so that
+	// they're not skipped as duplicates when the campaign is retried.
+	Forget(campaign string, userIDs []string) error
+	Release(run string) error
+}
+
+type redisTracker struct {
+	pool   *redis.Pool
+	prefix string
+	window time.Duration
+	dryRun bool
+}
+
+// NewRedisTracker returns a Tracker keeping its state in redis so that large files do not have to
+// fit in memory. Across files, a user is a duplicate if they were enqueued for the same campaign within
+// window. A window of zero or less only deduplicates within a file.
+func NewRedisTracker(pool *redis.Pool, prefix string, window time.Duration) Tracker {
+	return &redisTracker{pool: pool, prefix: prefix, window: window}
+}
+
+// NewDryRunTracker returns a Tracker like NewRedisTracker that never records users for a campaign,
+// so that validating a file does not affect later runs.
+func NewDryRunTracker(pool *redis.Pool, prefix string, window time.Duration) Tracker {
+	return &redisTracker{pool: pool, prefix: prefix, window: window, dryRun: true}
+}
+
+// Check marks userID as seen for the file run and campaign.
+func (t *redisTracker) Check(run, campaign, userID string) (Result, error) {
+	return t.checkOne(run, campaign, userID, false)
+}
+
+// CheckResend marks userID as seen for the file run and campaign, whether the campaign already had it or not.
+func (t *redisTracker) CheckResend(run, campaign, userID string) (Result, error) {
+	return t.checkOne(run, campaign, userID, true)
+}
+
+// CheckMany marks userIDs as seen for the file run and campaign.
+func (t *redisTracker) CheckMany(run, campaign string, userIDs []string) ([]Result, error) {
+	return t.check(run, campaign, userIDs, false)
+}
+
+// CheckResendMany marks userIDs as seen for the file run and campaign, whether the campaign already had them or not.
+func (t *redisTracker) CheckResendMany(run, campaign string, userIDs []string) ([]Result, error) {
+	return t.check(run, campaign, userIDs, true)
+}
+
+func (t *redisTracker) checkOne(run, campaign, userID string, resend bool) (Result, error) {
+	results, err := t.check(run, campaign, []string{userID}, resend)
+	if err != nil {
+		return Unique, err
+	}
+	return results[0], nil
+}
+
+func (t *redisTracker) check(run, campaign string, userIDs []string, resend bool) ([]Result, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	conn := t.pool.Get()
+	defer conn.Close()
+
+	args := redis.Args{}.
+		Add(t.prefix+fileKeyPrefix+run, t.prefix+campaignKeyPrefix+campaign).
+		Add(fileTTL.Milliseconds(), t.window.Milliseconds(), time.Now().UnixMilli(), t.dryRun, resend).
+		AddFlat(userIDs)
+	values, err := redis.Ints(checkScript.Do(conn, args...))
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(values))
+	for i, value := range values {
+		results[i] = Result(value)
+	}
+	return results, nil
+}
+
+// Forget drops userIDs from the users enqueued for campaign. The campaign is left alone on dry runs, which
+// never recorded them.
+func (t *redisTracker) Forget(campaign string, userIDs []string) error {
+	if t.dryRun || t.window <= 0 || len(userIDs) == 0 {
+		return nil
+	}
+	conn := t.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("ZREM", redis.Args{}.Add(t.prefix+campaignKeyPrefix+campaign).AddFlat(userIDs)...)
+	return err
+}
+
+// Release drops the state of a file run once it is processed.
+func (t *redisTracker) Release(run string) error {
+	conn := t.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", t.prefix+fileKeyPrefix+run)
+	return err
+}
+
+// MemoryTracker is a Tracker like the redis one keeping its state in memory, for running without redis.
+// It's lost when the process exits.
+type MemoryTracker struct {
+	window time.Duration
+	now    func() time.Time
+	dryRun bool
+
+	mutex     *sync.Mutex
+	runs      map[string]map[string]struct{}
+	campaigns map[string]map[string]time.Time
+}
+
+// NewMemoryTracker returns a MemoryTracker deduplicating users across files of a campaign within window.
+// A window of zero or less only deduplicates within a file.
+func NewMemoryTracker(window time.Duration) *MemoryTracker {
+	return &MemoryTracker{
+		window:    window,
+		now:       time.Now,
+		mutex:     &sync.Mutex{},
+		runs:      make(map[string]map[string]struct{}),
+		campaigns: make(map[string]map[string]time.Time),
+	}
+}
+
+// DryRun returns a Tracker sharing the state of t that never records users for a campaign.
+func (t *MemoryTracker) DryRun() Tracker {
+	dryRun := *t
+	dryRun.dryRun = true
+	return &dryRun
+}
+
+func (t *MemoryTracker) Check(run, campaign, userID string) (Result, error) {
+	return t.check(run, campaign, userID, false), nil
+}
+
+func (t *MemoryTracker) CheckResend(run, campaign, userID string) (Result, error) {
+	return t.check(run, campaign, userID, true), nil
+}
+
+func (t *MemoryTracker) CheckMany(run, campaign string, userIDs []string) ([]Result, error) {
+	return t.checkMany(run, campaign, userIDs, false), nil
+}
+
+func (t *MemoryTracker) CheckResendMany(run, campaign string, userIDs []string) ([]Result, error) {
+	return t.checkMany(run, campaign, userIDs, true), nil
+}
+
+func (t *MemoryTracker) checkMany(run, campaign string, userIDs []string, resend bool) []Result {
+	results := make([]Result, len(userIDs))
+	for i, userID := range userIDs {
+		results[i] = t.check(run, campaign, userID, resend)
+	}
+	return results
+}
+
+func (t *MemoryTracker) check(run, campaign, userID string, resend bool) Result {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	seen, ok := t.runs[run]
+	if !ok {
+		seen = make(map[string]struct{})
+		t.runs[run] = seen
+	}
+	if _, ok := seen[userID]; ok {
+		return DuplicateInFile
+	}
+	seen[userID] = struct{}{}
+	if t.window <= 0 {
+		return Unique
+	}
+
+	now := t.now()
+	enqueued, ok := t.campaigns[campaign]
+	if last, seen := enqueued[userID]; seen && last.After(now.Add(-t.window)) && !resend {
+		return DuplicateInCampaign
+	}
+	if t.dryRun {
+		return Unique
+	}
+	if !ok {
+		enqueued = make(map[string]time.Time)
+		t.campaigns[campaign] = enqueued
+	}
+	enqueued[userID] = now
+	return Unique
+}
+
+func (t *MemoryTracker) Forget(campaign string, userIDs []string) error {
+	if t.dryRun {
+		return nil
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, userID := range userIDs {
+		delete(t.campaigns[campaign], userID)
+	}
+	return nil
+}
+
+// Release drops the state of a file run, and the users enqueued for a campaign before the window.
+func (t *MemoryTracker) Release(run string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.runs, run)
+	expired := t.now().Add(-t.window)
+	for campaign, enqueued := range t.campaigns {
+		for userID, last := range enqueued {
+			if !last.After(expired) {
+				delete(enqueued, userID)
+			}
+		}
+		if len(enqueued) == 0 {
+			delete(t.campaigns, campaign)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPool(t *testing.T) *redis.Pool {
+	server := miniredis.RunT(t)
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+}
+
+func TestRedisTracker_Check(t *testing.T) {
+	tracker := NewRedisTracker(newTestPool(t), "delivery:", time.Hour)
+
+	result, err := tracker.Check("file_a:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, Unique, result)
+
+	result, err = tracker.Check("file_a:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateInFile, result)
+
+	result, err = tracker.Check("file_b:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateInCampaign, result)
+
+	result, err = tracker.Check("file_c:1", "other_campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, Unique, result)
+}
+
+func TestRedisTracker_CheckMany(t *testing.T) {
+	tracker := NewRedisTracker(newTestPool(t), "delivery:", time.Hour)
+
+	_, err := tracker.Check("file_a:1", "campaign", "7")
+	require.NoError(t, err)
+
+	results, err := tracker.CheckMany("file_b:1", "campaign", []string{"42", "7", "43", "42"})
+	require.NoError(t, err)
+	assert.Equal(t, []Result{Unique, DuplicateInCampaign, Unique, DuplicateInFile}, results)
+
+	results, err = tracker.CheckResendMany("file_c:1", "campaign", []string{"42", "42"})
+	require.NoError(t, err)
+	assert.Equal(t, []Result{Unique, DuplicateInFile}, results)
+
+	results, err = tracker.CheckMany("file_d:1", "campaign", nil)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestRedisTracker_Release(t *testing.T) {
+	tracker := NewRedisTracker(newTestPool(t), "delivery:", 0)
+
+	_, err := tracker.Check("file_a:1", "campaign", "42")
+	require.NoError(t, err)
+	require.NoError(t, tracker.Release("file_a:1"))
+
+	result, err := tracker.Check("file_a:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, Unique, result)
+}
+
+func TestRedisTracker_CheckResend(t *testing.T) {
+	tracker := NewRedisTracker(newTestPool(t), "delivery:", time.Hour)
+
+	_, err := tracker.Check("file_a:1", "campaign", "42")
+	require.NoError(t, err)
+
+	result, err := tracker.CheckResend("file_b:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, Unique, result)
+
+	result, err = tracker.CheckResend("file_b:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateInFile, result)
+}
+
+func TestRedisTracker_Forget(t *testing.T) {
+	tracker := NewRedisTracker(newTestPool(t), "delivery:", time.Hour)
+
+	for _, userID := range []string{"41", "42", "43"} {
+		_, err := tracker.Check("file_a:1", "campaign", userID)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tracker.Forget("campaign", []string{"41", "42"}))
+
+	for userID, expected := range map[string]Result{"41": Unique, "42": Unique, "43": DuplicateInCampaign} {
+		result, err := tracker.Check("file_b:1", "campaign", userID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, result, userID)
+	}
+}
+
+func TestRedisTracker_CampaignWindowExpires(t *testing.T) {
+	tracker := NewRedisTracker(newTestPool(t), "delivery:", 50*time.Millisecond)
+
+	_, err := tracker.Check("file_a:1", "campaign", "42")
+	require.NoError(t, err)
+
+	time.Sleep(60 * time.Millisecond)
+
+	result, err := tracker.Check("file_b:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, Unique, result)
+}
+
+func TestRedisTracker_TrimsCampaign(t *testing.T) {
+	server := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+	tracker := NewRedisTracker(pool, "delivery:", 50*time.Millisecond)
+
+	_, err := tracker.Check("file_a:1", "campaign", "41")
+	require.NoError(t, err)
+
+	time.Sleep(60 * time.Millisecond)
+
+	_, err = tracker.Check("file_b:1", "campaign", "42")
+	require.NoError(t, err)
+	members, err := server.ZMembers("delivery:dedup:campaign:campaign")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"42"}, members)
+}
+
+func TestDryRunTracker_DoesNotRecordCampaign(t *testing.T) {
+	pool := newTestPool(t)
+	dryRun := NewDryRunTracker(pool, "delivery:", time.Hour)
+
+	result, err := dryRun.Check("file_a:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, Unique, result)
+
+	result, err = dryRun.Check("file_a:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateInFile, result)
+
+	result, err = NewRedisTracker(pool, "delivery:", time.Hour).Check("file_b:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, Unique, result)
+
+	result, err = dryRun.Check("file_c:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, DuplicateInCampaign, result)
+}
+
+func TestMemoryTracker_Check(t *testing.T) {
+	tracker := NewMemoryTracker(time.Hour)
+	now := time.Now()
+	tracker.now = func() time.Time { return now }
+	dryRun := tracker.DryRun()
+
+	checks := []struct {
+		tracker  Tracker
+		resend   bool
+		run      string
+		campaign string
+		expected Result
+	}{
+		{dryRun, false, "file_a:1", "campaign", Unique},
+		{tracker, false, "file_b:1", "campaign", Unique},
+		{tracker, false, "file_b:1", "campaign", DuplicateInFile},
+		{dryRun, false, "file_c:1", "campaign", DuplicateInCampaign},
+		{tracker, false, "file_d:1", "campaign", DuplicateInCampaign},
+		{tracker, true, "file_e:1", "campaign", Unique},
+		{tracker, false, "file_f:1", "other_campaign", Unique},
+	}
+	for i, c := range checks {
+		check := c.tracker.Check
+		if c.resend {
+			check = c.tracker.CheckResend
+		}
+		result, err := check(c.run, c.campaign, "42")
+		require.NoError(t, err)
+		assert.Equal(t, c.expected, result, i)
+	}
+
+	require.NoError(t, tracker.Release("file_b:1"))
+	require.NoError(t, tracker.Forget("campaign", []string{"42"}))
+	result, err := tracker.Check("file_b:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, Unique, result)
+
+	now = now.Add(2 * time.Hour)
+	result, err = tracker.Check("file_g:1", "campaign", "42")
+	require.NoError(t, err)
+	assert.Equal(t, Unique, result)
+
+	now = now.Add(2 * time.Hour)
+	require.NoError(t, tracker.Release("file_g:1"))
+	assert.Empty(t, tracker.campaigns)
+}
@@ -0,0 +1,122 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: swilly-delivery-service/internal/pkg/dedup (interfaces: Tracker)
+
+// Package dedup is a generated GoMock package.
+package dedup
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockTracker is a mock of Tracker interface.
+type MockTracker struct {
+	ctrl     *gomock.Controller
+	recorder *MockTrackerMockRecorder
+}
+
+// MockTrackerMockRecorder is the mock recorder for MockTracker.
+type MockTrackerMockRecorder struct {
+	mock *MockTracker
+}
+
+// NewMockTracker creates a new mock instance.
+func NewMockTracker(ctrl *gomock.Controller) *MockTracker {
+	mock := &MockTracker{ctrl: ctrl}
+	mock.recorder = &MockTrackerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTracker) EXPECT() *MockTrackerMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockTracker) Check(arg0, arg1, arg2 string) (Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", arg0, arg1, arg2)
+	ret0, _ := ret[0].(Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockTrackerMockRecorder) Check(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockTracker)(nil).Check), arg0, arg1, arg2)
+}
+
+// CheckMany mocks base method.
+func (m *MockTracker) CheckMany(arg0, arg1 string, arg2 []string) ([]Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckMany", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckMany indicates an expected call of CheckMany.
+func (mr *MockTrackerMockRecorder) CheckMany(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckMany", reflect.TypeOf((*MockTracker)(nil).CheckMany), arg0, arg1, arg2)
+}
+
+// CheckResend mocks base method.
+func (m *MockTracker) CheckResend(arg0, arg1, arg2 string) (Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckResend", arg0, arg1, arg2)
+	ret0, _ := ret[0].(Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckResend indicates an expected call of CheckResend.
+func (mr *MockTrackerMockRecorder) CheckResend(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckResend", reflect.TypeOf((*MockTracker)(nil).CheckResend), arg0, arg1, arg2)
+}
+
+// CheckResendMany mocks base method.
+func (m *MockTracker) CheckResendMany(arg0, arg1 string, arg2 []string) ([]Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckResendMany", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckResendMany indicates an expected call of CheckResendMany.
+func (mr *MockTrackerMockRecorder) CheckResendMany(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckResendMany", reflect.TypeOf((*MockTracker)(nil).CheckResendMany), arg0, arg1, arg2)
+}
+
+// Forget mocks base method.
+func (m *MockTracker) Forget(arg0 string, arg1 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Forget", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Forget indicates an expected call of Forget.
+func (mr *MockTrackerMockRecorder) Forget(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Forget", reflect.TypeOf((*MockTracker)(nil).Forget), arg0, arg1)
+}
+
+// Release mocks base method.
+func (m *MockTracker) Release(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockTrackerMockRecorder) Release(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockTracker)(nil).Release), arg0)
+}
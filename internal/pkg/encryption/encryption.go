@@ -0,0 +1,59 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// Suffix names a file encrypted with age after the file it encrypts.
+const Suffix = ".age"
+
+var ErrArmored = errors.New("ASCII armored files are not supported, encrypt without --armor")
+
+const armorHeader = "-----BEGIN AGE ENCRYPTED FILE-----"
+
+// Decrypter decrypts files encrypted with age to any of its identities.
+type Decrypter struct {
+	identities []age.Identity
+}
+
+// IsEncrypted tells whether the file is named as encrypted with age.
+func IsEncrypted(name string) bool {
+	return strings.HasSuffix(name, Suffix)
+}
+
+// LoadDecrypter reads the identities at path, see ParseDecrypter.
+func LoadDecrypter(path string) (*Decrypter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseDecrypter(file)
+}
+
+// ParseDecrypter reads an age identity file, one "AGE-SECRET-KEY-1..." identity per line. Blank lines and
+// lines starting with # are ignored.
+func ParseDecrypter(r io.Reader) (*Decrypter, error) {
+	identities, err := age.ParseIdentities(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Decrypter{identities: identities}, nil
+}
+
+// Decrypt returns the plaintext of the size bytes of age encrypted content read from src, and its size.
+// The plaintext is decrypted as it's read, a chunk at a time, so that it's never held in memory or
+// written anywhere as a whole. The returned ReaderAt may be read concurrently.
+func (d *Decrypter) Decrypt(src io.ReaderAt, size int64) (io.ReaderAt, int64, error) {
+	header := make([]byte, len(armorHeader))
+	if n, _ := src.ReadAt(header, 0); bytes.Equal(header[:n], []byte(armorHeader)) {
+		return nil, 0, ErrArmored
+	}
+	return age.DecryptReaderAt(src, size, d.identities...)
+}
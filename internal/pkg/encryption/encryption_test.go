@@ -0,0 +1,85 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encrypt(t *testing.T, recipient age.Recipient, plaintext string) []byte {
+	var encrypted bytes.Buffer
+	w, err := age.Encrypt(&encrypted, recipient)
+	require.NoError(t, err)
+	_, err = io.WriteString(w, plaintext)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return encrypted.Bytes()
+}
+
+func TestDecrypter_Decrypt(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	decrypter, err := ParseDecrypter(strings.NewReader("# support team\n" + other.String() + "\n" + identity.String() + "\n"))
+	require.NoError(t, err)
+
+	plaintext := strings.Repeat("1\n2\n3\n", 20000)
+	encrypted := encrypt(t, identity.Recipient(), plaintext)
+	content, size, err := decrypter.Decrypt(bytes.NewReader(encrypted), int64(len(encrypted)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(plaintext)), size)
+	decrypted, err := io.ReadAll(io.NewSectionReader(content, 0, size))
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, string(decrypted))
+
+	// Reading from the middle of the content, as chunks of the file are
+	part := make([]byte, 6)
+	_, err = content.ReadAt(part, 6*10000)
+	require.NoError(t, err)
+	assert.Equal(t, "1\n2\n3\n", string(part))
+}
+
+func TestDecrypter_DecryptInvalid(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	decrypter, err := ParseDecrypter(strings.NewReader(identity.String()))
+	require.NoError(t, err)
+
+	encrypted := encrypt(t, other.Recipient(), "1\n")
+	_, _, err = decrypter.Decrypt(bytes.NewReader(encrypted), int64(len(encrypted)))
+	var noMatch *age.NoIdentityMatchError
+	assert.True(t, errors.As(err, &noMatch))
+
+	encrypted = encrypt(t, identity.Recipient(), "1\n")
+	encrypted[len(encrypted)-1] ^= 1
+	_, _, err = decrypter.Decrypt(bytes.NewReader(encrypted), int64(len(encrypted)))
+	assert.Error(t, err)
+
+	var armored bytes.Buffer
+	w := armor.NewWriter(&armored)
+	_, err = w.Write(encrypt(t, identity.Recipient(), "1\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	_, _, err = decrypter.Decrypt(bytes.NewReader(armored.Bytes()), int64(armored.Len()))
+	assert.True(t, errors.Is(err, ErrArmored))
+
+	_, _, err = decrypter.Decrypt(strings.NewReader("1\n2\n"), 4)
+	assert.Error(t, err)
+}
+
+func TestParseDecrypter_Invalid(t *testing.T) {
+	for _, identities := range []string{"", "# nobody\n", "AGE-SECRET-KEY-1NOTAKEY\n"} {
+		_, err := ParseDecrypter(strings.NewReader(identities))
+		assert.Error(t, err, identities)
+	}
+}
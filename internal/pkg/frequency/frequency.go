@@ -0,0 +1,113 @@
+package frequency
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const keyPrefix = "frequency:"
+
+// slidingWindowScript trims entries older than the window and records a new one if the user is
+// still under the limit. It returns 0 when allowed, otherwise the milliseconds until a slot frees up.
+var slidingWindowScript = redis.NewScript(1, `
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[4])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return 0
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return tonumber(oldest[2]) + window - now
+`)
+
+// Decision is the outcome of a frequency cap check.
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
+
+// Limiter caps how many messages a user receives within a rolling window.
+type Limiter interface {
+	Allow(userID string) (Decision, error)
+}
+
+type redisLimiter struct {
+	pool   *redis.Pool
+	prefix string
+	limit  int
+	window time.Duration
+}
+
+// NewRedisLimiter returns a Limiter allowing at most limit messages per user in any window,
+// tracked as a sorted set of send timestamps per user. A limit of zero or less disables capping.
+func NewRedisLimiter(pool *redis.Pool, prefix string, limit int, window time.Duration) Limiter {
+	return &redisLimiter{pool: pool, prefix: prefix, limit: limit, window: window}
+}
+
+// Allow records a send for the user if it fits in the window.
+func (l *redisLimiter) Allow(userID string) (Decision, error) {
+	if l.limit <= 0 {
+		return Decision{Allowed: true}, nil
+	}
+
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	now := time.Now()
+	wait, err := redis.Int64(slidingWindowScript.Do(conn,
+		l.prefix+keyPrefix+userID,
+		now.UnixMilli(),
+		l.window.Milliseconds(),
+		l.limit,
+		strconv.FormatInt(now.UnixNano(), 10),
+	))
+	if err != nil {
+		return Decision{}, err
+	}
+	if wait <= 0 {
+		return Decision{Allowed: true}, nil
+	}
+	return Decision{RetryAfter: time.Duration(wait) * time.Millisecond}, nil
+}
+
+type memoryLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mutex sync.Mutex
+	sends map[string][]time.Time
+}
+
+// NewMemoryLimiter returns a Limiter like NewRedisLimiter keeping the send timestamps in memory, for
+// running without redis.
+func NewMemoryLimiter(limit int, window time.Duration) Limiter {
+	return &memoryLimiter{limit: limit, window: window, now: time.Now, sends: make(map[string][]time.Time)}
+}
+
+func (l *memoryLimiter) Allow(userID string) (Decision, error) {
+	if l.limit <= 0 {
+		return Decision{Allowed: true}, nil
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	sends := l.sends[userID]
+	for len(sends) > 0 && !sends[0].After(now.Add(-l.window)) {
+		sends = sends[1:]
+	}
+	if len(sends) < l.limit {
+		l.sends[userID] = append(sends, now)
+		return Decision{Allowed: true}, nil
+	}
+	l.sends[userID] = sends
+	return Decision{RetryAfter: sends[0].Add(l.window).Sub(now)}, nil
+}
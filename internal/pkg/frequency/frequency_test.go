@@ -0,0 +1,88 @@
+package frequency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPool(t *testing.T) *redis.Pool {
+	server := miniredis.RunT(t)
+	return &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+}
+
+func TestRedisLimiter_Allow(t *testing.T) {
+	limiter := NewRedisLimiter(newTestPool(t), "delivery:", 2, time.Hour)
+
+	for i := 0; i < 2; i++ {
+		decision, err := limiter.Allow("42")
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+	}
+
+	decision, err := limiter.Allow("42")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.True(t, decision.RetryAfter > 59*time.Minute && decision.RetryAfter <= time.Hour)
+
+	decision, err = limiter.Allow("43")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
+
+func TestRedisLimiter_WindowSlides(t *testing.T) {
+	limiter := NewRedisLimiter(newTestPool(t), "delivery:", 1, 50*time.Millisecond)
+
+	decision, err := limiter.Allow("42")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	time.Sleep(60 * time.Millisecond)
+
+	decision, err = limiter.Allow("42")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
+
+func TestRedisLimiter_Disabled(t *testing.T) {
+	limiter := NewRedisLimiter(nil, "delivery:", 0, time.Hour)
+
+	decision, err := limiter.Allow("42")
+	assert.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	limiter := NewMemoryLimiter(2, time.Hour).(*memoryLimiter)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		decision, err := limiter.Allow("42")
+		require.NoError(t, err)
+		assert.True(t, decision.Allowed)
+	}
+
+	now = now.Add(time.Minute)
+	decision, err := limiter.Allow("42")
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, 59*time.Minute, decision.RetryAfter)
+
+	decision, err = limiter.Allow("43")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+
+	now = now.Add(59 * time.Minute)
+	decision, err = limiter.Allow("42")
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+}
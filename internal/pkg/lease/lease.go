@@ -0,0 +1,144 @@
+package lease
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const keyPrefix = "lease:"
+
+// renewScript extends the lease only if it is still held by the owner in ARGV[1].
+var renewScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript drops the lease only if it is still held by the owner in ARGV[1].
+var releaseScript = redis.NewScript(1, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// Locker hands out named leases shared by every instance of the service. A lease expires unless
+// renewed, so that another instance can take over when the holder dies.
+type Locker interface {
+	// Acquire takes the lease on name, returning false if it is held, including by this Locker.
+	Acquire(name string) (bool, error)
+	// Renew extends a lease held by this Locker, returning false if it was lost in the meantime.
+	Renew(name string) (bool, error)
+	// Release drops a lease held by this Locker.
+	Release(name string) error
+	// TTL is how long a lease lasts unless renewed.
+	TTL() time.Duration
+}
+
+type redisLocker struct {
+	pool   *redis.Pool
+	prefix string
+	ttl    time.Duration
+	owner  string
+}
+
+// NewRedisLocker returns a Locker whose leases last ttl, held in the name of this process.
+func NewRedisLocker(pool *redis.Pool, prefix string, ttl time.Duration) Locker {
+	return &redisLocker{pool: pool, prefix: prefix, ttl: ttl, owner: newOwner()}
+}
+
+// newOwner identifies this process among the instances of the service.
+func newOwner() string {
+	host, _ := os.Hostname()
+	nonce := make([]byte, 8)
+	_, _ = rand.Read(nonce)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(nonce))
+}
+
+func (l *redisLocker) Acquire(name string) (bool, error) {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	_, err := redis.String(conn.Do("SET", l.prefix+keyPrefix+name, l.owner, "NX", "PX", l.ttl.Milliseconds()))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *redisLocker) Renew(name string) (bool, error) {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	renewed, err := redis.Int(renewScript.Do(conn, l.prefix+keyPrefix+name, l.owner, l.ttl.Milliseconds()))
+	return renewed == 1, err
+}
+
+func (l *redisLocker) Release(name string) error {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	_, err := releaseScript.Do(conn, l.prefix+keyPrefix+name, l.owner)
+	return err
+}
+
+func (l *redisLocker) TTL() time.Duration {
+	return l.ttl
+}
+
+type memoryLocker struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mutex  sync.Mutex
+	leases map[string]time.Time
+}
+
+// NewMemoryLocker returns a Locker whose leases last ttl, for running a single instance without redis.
+// Its leases are only shared within the process.
+func NewMemoryLocker(ttl time.Duration) Locker {
+	return &memoryLocker{ttl: ttl, now: time.Now, leases: make(map[string]time.Time)}
+}
+
+func (l *memoryLocker) Acquire(name string) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if expires, ok := l.leases[name]; ok && now.Before(expires) {
+		return false, nil
+	}
+	l.leases[name] = now.Add(l.ttl)
+	return true, nil
+}
+
+func (l *memoryLocker) Renew(name string) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if expires, ok := l.leases[name]; !ok || !now.Before(expires) {
+		return false, nil
+	}
+	l.leases[name] = now.Add(l.ttl)
+	return true, nil
+}
+
+func (l *memoryLocker) Release(name string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.leases, name)
+	return nil
+}
+
+func (l *memoryLocker) TTL() time.Duration {
+	return l.ttl
+}
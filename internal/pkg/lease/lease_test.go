@@ -0,0 +1,107 @@
+package lease
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPool(t *testing.T) (*miniredis.Miniredis, *redis.Pool) {
+	server := miniredis.RunT(t)
+	return server, &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+}
+
+func TestRedisLocker_Acquire(t *testing.T) {
+	_, pool := newTestPool(t)
+	first := NewRedisLocker(pool, "delivery:", time.Minute)
+	second := NewRedisLocker(pool, "delivery:", time.Minute)
+
+	acquired, err := first.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = first.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	acquired, err = second.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// Only the holder can release the lease
+	require.NoError(t, second.Release("swilly_file"))
+	acquired, err = second.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	require.NoError(t, first.Release("swilly_file"))
+	acquired, err = second.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestRedisLocker_Failover(t *testing.T) {
+	server, pool := newTestPool(t)
+	first := NewRedisLocker(pool, "delivery:", time.Minute)
+	second := NewRedisLocker(pool, "delivery:", time.Minute)
+
+	acquired, err := first.Acquire("swilly_file")
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	server.FastForward(30 * time.Second)
+	renewed, err := first.Renew("swilly_file")
+	require.NoError(t, err)
+	assert.True(t, renewed)
+
+	// The holder died and stopped renewing
+	server.FastForward(time.Minute)
+	acquired, err = second.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	renewed, err = first.Renew("swilly_file")
+	require.NoError(t, err)
+	assert.False(t, renewed)
+}
+
+func TestMemoryLocker(t *testing.T) {
+	locker := NewMemoryLocker(time.Minute).(*memoryLocker)
+	now := time.Now()
+	locker.now = func() time.Time { return now }
+
+	acquired, err := locker.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = locker.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	now = now.Add(30 * time.Second)
+	renewed, err := locker.Renew("swilly_file")
+	require.NoError(t, err)
+	assert.True(t, renewed)
+
+	now = now.Add(time.Minute)
+	renewed, err = locker.Renew("swilly_file")
+	require.NoError(t, err)
+	assert.False(t, renewed)
+
+	acquired, err = locker.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	require.NoError(t, locker.Release("swilly_file"))
+	acquired, err = locker.Acquire("swilly_file")
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
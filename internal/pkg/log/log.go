@@ -28,6 +28,8 @@ var (
 	output   zapcore.WriteSyncer
 	logLevel zapcore.Level
 	log      *zap.Logger
+	// auditLog writes the entries of the audit log, marked with the audit field and kept whatever the log level
+	auditLog *zap.Logger
 	logMu    sync.Mutex
 )
 
@@ -41,6 +43,7 @@ func createLogger(o zapcore.WriteSyncer, lvl zapcore.Level) {
 	output = o
 	core := zapcore.NewCore(encoder, output, lvl)
 	log = zap.New(core).WithOptions(loggerOpts...)
+	auditLog = zap.New(zapcore.NewCore(encoder, output, zap.InfoLevel)).WithOptions(loggerOpts...).With(zap.Bool("audit", true))
 }
 
 func defaultLog() {
@@ -100,6 +103,11 @@ func Panic(msg string, fields ...zap.Field) {
 	log.Panic(msg, fields...)
 }
 
+// Audit add entry with or without fields to the audit log, recording who did what
+func Audit(msg string, fields ...zap.Field) {
+	auditLog.Info(msg, fields...)
+}
+
 type Logger struct {
 	log *zap.Logger
 }
@@ -138,3 +138,20 @@ func TestLogging(t *testing.T) {
 		})
 	}
 }
+
+func TestAudit(t *testing.T) {
+	buf.Reset()
+	SetLogLevel("error")
+	defer SetLogLevel("info")
+
+	Audit("test message", zap.String("signer", "alice"))
+
+	logMap := make(map[string]interface{})
+	err := json.NewDecoder(buf).Decode(&logMap)
+	assert.Nil(t, err, "Error should nil while decoding log to json")
+
+	assert.Equal(t, "test message", logMap["message"], "Should be equal")
+	assert.Equal(t, "info", logMap["level"], "Should be equal")
+	assert.Equal(t, true, logMap["audit"], "Should be equal")
+	assert.Equal(t, "alice", logMap["signer"], "Should be equal")
+}
@@ -0,0 +1,130 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// A manifest is written next to the file it describes, named after it with one of these suffixes.
+const (
+	JSONSuffix = ".manifest.json"
+	YAMLSuffix = ".manifest.yaml"
+	YMLSuffix  = ".manifest.yml"
+)
+
+var Suffixes = []string{JSONSuffix, YAMLSuffix, YMLSuffix}
+
+// Channels are the channels a campaign can be sent on.
+var Channels = []string{"push", "sms", "email"}
+
+// Manifest describes the campaign a dropped file belongs to.
+type Manifest struct {
+	Campaign string `json:"campaign" yaml:"campaign"`
+	// Owner is who to reach about the campaign
+	Owner    string `json:"owner" yaml:"owner"`
+	Template string `json:"template" yaml:"template"`
+	Channel  string `json:"channel" yaml:"channel"`
+	// SendAt is when the campaign is meant to be sent, if scheduled
+	SendAt   *time.Time `json:"send_at,omitempty" yaml:"send_at"`
+	Priority int        `json:"priority" yaml:"priority"`
+	// ExpiresAt is when the campaign is no longer worth sending, if ever
+	ExpiresAt *time.Time `json:"expires_at,omitempty" yaml:"expires_at"`
+	// ExpectedRows is the number of lines of the file
+	ExpectedRows *int64 `json:"expected_rows" yaml:"expected_rows"`
+	// Resend is set when the file is deliberately sent again, to users the campaign was already sent to
+	Resend bool `json:"resend,omitempty" yaml:"resend"`
+}
+
+// IsManifest tells whether the file named name is a manifest.
+func IsManifest(name string) bool {
+	return Suffix(name) != ""
+}
+
+// Suffix returns the manifest suffix name ends with, if any.
+func Suffix(name string) string {
+	for _, suffix := range Suffixes {
+		if strings.HasSuffix(name, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// Read reads the manifest at path, see Parse.
+func Read(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse parses data, the manifest at path, as JSON or YAML depending on the suffix of path. Unknown
+// fields are rejected so that misspelled ones don't go unnoticed.
+func Parse(path string, data []byte) (*Manifest, error) {
+	var manifest Manifest
+	var err error
+	if Suffix(path) == JSONSuffix {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&manifest)
+	} else {
+		err = yaml.UnmarshalStrict(data, &manifest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// Validate checks that the manifest describes a campaign that can be sent, reporting every problem found.
+func (m *Manifest) Validate() error {
+	var problems []string
+	required := []struct{ field, value string }{{"campaign", m.Campaign}, {"owner", m.Owner}, {"template", m.Template}}
+	for _, r := range required {
+		if strings.TrimSpace(r.value) == "" {
+			problems = append(problems, r.field+" is required")
+		}
+	}
+	if !isChannel(m.Channel) {
+		problems = append(problems, fmt.Sprintf("channel must be one of %s", strings.Join(Channels, ", ")))
+	}
+	if m.Priority < 0 {
+		problems = append(problems, "priority must not be negative")
+	}
+	if m.ExpiresAt != nil && m.SendAt != nil && !m.ExpiresAt.After(*m.SendAt) {
+		problems = append(problems, "expires_at must be after send_at")
+	}
+	switch {
+	case m.ExpectedRows == nil:
+		problems = append(problems, "expected_rows is required")
+	case *m.ExpectedRows < 0:
+		problems = append(problems, "expected_rows must not be negative")
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// Expired tells whether the campaign is no longer worth sending at now.
+func (m *Manifest) Expired(now time.Time) bool {
+	return m.ExpiresAt != nil && !now.Before(*m.ExpiresAt)
+}
+
+func isChannel(channel string) bool {
+	for _, known := range Channels {
+		if channel == known {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeManifest(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestRead(t *testing.T) {
+	sendAt := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+	expiresAt := time.Date(2026, 10, 21, 9, 0, 0, 0, time.UTC)
+	rows := int64(1000)
+	expected := &Manifest{
+		Campaign:     "diwali_sale",
+		Owner:        "growth@swilly.in",
+		Template:     "Lights, sweets and 40% off",
+		Channel:      "push",
+		SendAt:       &sendAt,
+		Priority:     5,
+		ExpiresAt:    &expiresAt,
+		ExpectedRows: &rows,
+		Resend:       true,
+	}
+
+	manifest, err := Read(writeManifest(t, "swilly_diwali"+JSONSuffix, `{
+		"campaign": "diwali_sale", "owner": "growth@swilly.in", "template": "Lights, sweets and 40% off",
+		"channel": "push", "send_at": "2026-10-20T09:00:00Z", "priority": 5,
+		"expires_at": "2026-10-21T09:00:00Z", "expected_rows": 1000, "resend": true
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, expected, manifest)
+
+	manifest, err = Read(writeManifest(t, "swilly_diwali"+YAMLSuffix, `
+campaign: diwali_sale
+owner: growth@swilly.in
+template: Lights, sweets and 40% off
+channel: push
+send_at: 2026-10-20T09:00:00Z
+priority: 5
+expires_at: 2026-10-21T09:00:00Z
+expected_rows: 1000
+resend: true
+`))
+	require.NoError(t, err)
+	assert.Equal(t, expected, manifest)
+}
+
+func TestRead_RejectsUnknownFields(t *testing.T) {
+	_, err := Read(writeManifest(t, "swilly_diwali"+JSONSuffix, `{"campaign": "diwali_sale", "chanel": "push"}`))
+	assert.Error(t, err)
+
+	_, err = Read(writeManifest(t, "swilly_diwali"+YMLSuffix, "campaign: diwali_sale\nchanel: push\n"))
+	assert.Error(t, err)
+}
+
+func TestManifest_Validate(t *testing.T) {
+	sendAt := time.Date(2026, 10, 20, 9, 0, 0, 0, time.UTC)
+	rows := int64(-1)
+	manifest := &Manifest{Campaign: "diwali_sale", Channel: "fax", Priority: -1, SendAt: &sendAt, ExpiresAt: &sendAt, ExpectedRows: &rows}
+
+	assert.EqualError(t, manifest.Validate(), "owner is required; template is required; channel must be one of push, sms, email; "+
+		"priority must not be negative; expires_at must be after send_at; expected_rows must not be negative")
+
+	rows = 10
+	manifest.Owner, manifest.Template, manifest.Channel, manifest.Priority, manifest.ExpiresAt = "growth@swilly.in", "Hi", "sms", 0, nil
+	assert.NoError(t, manifest.Validate())
+}
+
+func TestManifest_Expired(t *testing.T) {
+	expiresAt := time.Date(2026, 10, 21, 9, 0, 0, 0, time.UTC)
+	manifest := &Manifest{}
+	assert.False(t, manifest.Expired(expiresAt))
+
+	manifest.ExpiresAt = &expiresAt
+	assert.False(t, manifest.Expired(expiresAt.Add(-time.Second)))
+	assert.True(t, manifest.Expired(expiresAt))
+}
@@ -0,0 +1,169 @@
+package profile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"swilly-delivery-service/internal/pkg/log"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+)
+
+const (
+	lookupPath     = "/profiles/lookup"
+	cacheKeyPrefix = "profile:"
+)
+
+// Profile is what the user-profile API knows about a user.
+type Profile struct {
+	UserID   string `json:"user_id"`
+	Locale   string `json:"locale"`
+	Timezone string `json:"timezone"`
+	// Channels are the channels the user accepts messages on, in order of preference
+	Channels []string `json:"channels"`
+	Active   bool     `json:"active"`
+}
+
+// Client looks up the profiles of users.
+type Client interface {
+	// Lookup returns the profiles of the users it knows of among userIDs, by user ID.
+	Lookup(userIDs []string) (map[string]Profile, error)
+}
+
+type lookupRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
+
+type lookupResponse struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+type httpClient struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPClient returns a Client of the user-profile API at baseURL, looking users up in one request with
+// POST /profiles/lookup.
+func NewHTTPClient(baseURL string, timeout time.Duration) Client {
+	return &httpClient{
+		url:    strings.TrimSuffix(baseURL, "/") + lookupPath,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *httpClient) Lookup(userIDs []string) (map[string]Profile, error) {
+	body, err := json.Marshal(lookupRequest{UserIDs: userIDs})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("user-profile API responded %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	var response lookupResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid user-profile API response: %w", err)
+	}
+
+	profiles := make(map[string]Profile, len(response.Profiles))
+	for _, profile := range response.Profiles {
+		profiles[profile.UserID] = profile
+	}
+	return profiles, nil
+}
+
+type cachedClient struct {
+	client Client
+	pool   *redis.Pool
+	prefix string
+	ttl    time.Duration
+}
+
+// NewCachedClient returns a Client asking client only for the profiles not cached in redis, and caching
+// those it returns for ttl. Users client doesn't know of are not cached.
+func NewCachedClient(client Client, pool *redis.Pool, prefix string, ttl time.Duration) Client {
+	return &cachedClient{client: client, pool: pool, prefix: prefix, ttl: ttl}
+}
+
+func (c *cachedClient) Lookup(userIDs []string) (map[string]Profile, error) {
+	profiles, missing := c.cached(userIDs)
+	if len(missing) == 0 {
+		return profiles, nil
+	}
+
+	found, err := c.client.Lookup(missing)
+	if err != nil {
+		return nil, err
+	}
+	for userID, profile := range found {
+		profiles[userID] = profile
+	}
+	if err := c.cache(found); err != nil {
+		log.Error("unable to cache user profiles", zap.Error(err))
+	}
+	return profiles, nil
+}
+
+// cached returns the cached profiles among userIDs and the user IDs left to look up. Redis being
+// unavailable leaves them all to look up.
+func (c *cachedClient) cached(userIDs []string) (map[string]Profile, []string) {
+	profiles := make(map[string]Profile, len(userIDs))
+	keys := make([]interface{}, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keys = append(keys, c.prefix+cacheKeyPrefix+userID)
+	}
+
+	conn := c.pool.Get()
+	defer conn.Close()
+	values, err := redis.ByteSlices(conn.Do("MGET", keys...))
+	if err != nil {
+		log.Error("unable to read cached user profiles", zap.Error(err))
+		return profiles, userIDs
+	}
+
+	var missing []string
+	for i, value := range values {
+		var profile Profile
+		if value == nil || json.Unmarshal(value, &profile) != nil {
+			missing = append(missing, userIDs[i])
+			continue
+		}
+		profiles[userIDs[i]] = profile
+	}
+	return profiles, missing
+}
+
+func (c *cachedClient) cache(profiles map[string]Profile) error {
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	conn := c.pool.Get()
+	defer conn.Close()
+	if err := conn.Send("MULTI"); err != nil {
+		return err
+	}
+	for userID, profile := range profiles {
+		data, err := json.Marshal(profile)
+		if err != nil {
+			return err
+		}
+		if err := conn.Send("SET", c.prefix+cacheKeyPrefix+userID, data, "PX", c.ttl.Milliseconds()); err != nil {
+			return err
+		}
+	}
+	_, err := conn.Do("EXEC")
+	return err
+}
@@ -0,0 +1,97 @@
+package profile
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStubAPI serves the profiles of users from known, counting the user IDs it's asked for.
+func newStubAPI(t *testing.T, known map[string]Profile, asked *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != lookupPath {
+			http.NotFound(w, r)
+			return
+		}
+		var request lookupRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		asked.Add(int32(len(request.UserIDs)))
+
+		response := lookupResponse{Profiles: []Profile{}}
+		for _, userID := range request.UserIDs {
+			if profile, ok := known[userID]; ok {
+				response.Profiles = append(response.Profiles, profile)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+var knownProfiles = map[string]Profile{
+	"1": {UserID: "1", Locale: "en_IN", Timezone: "Asia/Kolkata", Channels: []string{"push", "sms"}, Active: true},
+	"2": {UserID: "2", Locale: "hi_IN", Timezone: "Asia/Kolkata", Channels: []string{"sms"}, Active: false},
+}
+
+func TestHTTPClient_Lookup(t *testing.T) {
+	var asked atomic.Int32
+	server := newStubAPI(t, knownProfiles, &asked)
+	client := NewHTTPClient(server.URL+"/", time.Second)
+
+	profiles, err := client.Lookup([]string{"1", "2", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Profile{"1": knownProfiles["1"], "2": knownProfiles["2"]}, profiles)
+	assert.EqualValues(t, 3, asked.Load())
+}
+
+func TestHTTPClient_LookupError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPClient(server.URL, time.Second).Lookup([]string{"1"})
+	assert.EqualError(t, err, "user-profile API responded 503 Service Unavailable: overloaded")
+}
+
+func TestCachedClient_Lookup(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", redisServer.Addr())
+		},
+	}
+	var asked atomic.Int32
+	server := newStubAPI(t, knownProfiles, &asked)
+	client := NewCachedClient(NewHTTPClient(server.URL, time.Second), pool, "delivery:", time.Hour)
+
+	profiles, err := client.Lookup([]string{"1", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Profile{"1": knownProfiles["1"]}, profiles)
+	assert.EqualValues(t, 2, asked.Load())
+	assert.Equal(t, time.Hour, redisServer.TTL("delivery:profile:1"))
+	assert.False(t, redisServer.Exists("delivery:profile:3"))
+
+	// Only the users not cached are looked up again
+	profiles, err = client.Lookup([]string{"1", "2", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Profile{"1": knownProfiles["1"], "2": knownProfiles["2"]}, profiles)
+	assert.EqualValues(t, 4, asked.Load())
+
+	redisServer.FastForward(time.Hour)
+	_, err = client.Lookup([]string{"1"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, asked.Load())
+}
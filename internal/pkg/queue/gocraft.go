@@ -0,0 +1,298 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"swilly-delivery-service/internal/pkg/log"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+)
+
+// jobContext is the gocraft context type of the jobs, which carry nothing beyond their arguments.
+type jobContext struct{}
+
+// GocraftBackend keeps the jobs in redis, run by a gocraft worker pool.
+type GocraftBackend struct {
+	redis     *redis.Pool
+	namespace string
+	enqueuer  *work.Enqueuer
+	client    *work.Client
+	pool      *work.WorkerPool
+	jobs      *inFlightJobs
+	// names are the registered job names, whose jobs left in progress are requeued on Drain
+	names []string
+}
+
+func NewGocraftBackend(pool *redis.Pool, namespace string, concurrency uint) *GocraftBackend {
+	b := &GocraftBackend{
+		redis:     pool,
+		namespace: namespace,
+		enqueuer:  work.NewEnqueuer(namespace, pool),
+		client:    work.NewClient(namespace, pool),
+		pool:      work.NewWorkerPool(jobContext{}, concurrency, namespace, pool),
+		jobs:      newInFlightJobs(),
+	}
+	b.pool.Middleware(b.runJob)
+	return b
+}
+
+func (b *GocraftBackend) Enqueue(jobName string, args map[string]interface{}) (*Job, error) {
+	job, err := b.enqueuer.Enqueue(jobName, args)
+	if err != nil {
+		return nil, err
+	}
+	return fromGocraft(job), nil
+}
+
+// EnqueueBatch pushes the jobs to their queue in a single pipelined transaction, the way the gocraft
+// enqueuer pushes one.
+func (b *GocraftBackend) EnqueueBatch(jobName string, args []map[string]interface{}) ([]*Job, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	conn := b.redis.Get()
+	defer conn.Close()
+
+	queueKey := gocraftJobsKey(b.namespace, jobName)
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("SADD", gocraftKnownJobsKey(b.namespace), jobName); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	jobs := make([]*Job, 0, len(args))
+	for _, jobArgs := range args {
+		id, err := newJobID()
+		if err != nil {
+			return nil, err
+		}
+		job := &work.Job{Name: jobName, ID: id, EnqueuedAt: now, Args: jobArgs}
+		raw, err := json.Marshal(job)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.Send("LPUSH", queueKey, raw); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, fromGocraft(job))
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (b *GocraftBackend) EnqueueIn(jobName string, secondsFromNow int64, args map[string]interface{}) (*Job, error) {
+	scheduled, err := b.enqueuer.EnqueueIn(jobName, secondsFromNow, args)
+	if err != nil {
+		return nil, err
+	}
+	job := fromGocraft(scheduled.Job)
+	job.RunAt = scheduled.RunAt
+	return job, nil
+}
+
+// EnqueueBatchIn adds the jobs to the scheduled ones in a single pipelined transaction, the way the
+// gocraft enqueuer schedules one.
+func (b *GocraftBackend) EnqueueBatchIn(jobName string, secondsFromNow int64, args []map[string]interface{}) ([]*Job, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	conn := b.redis.Get()
+	defer conn.Close()
+
+	if err := conn.Send("MULTI"); err != nil {
+		return nil, err
+	}
+	if err := conn.Send("SADD", gocraftKnownJobsKey(b.namespace), jobName); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	runAt := now + secondsFromNow
+	jobs := make([]*Job, 0, len(args))
+	for _, jobArgs := range args {
+		id, err := newJobID()
+		if err != nil {
+			return nil, err
+		}
+		job := &work.Job{Name: jobName, ID: id, EnqueuedAt: now, Args: jobArgs}
+		raw, err := json.Marshal(job)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.Send("ZADD", gocraftScheduledKey(b.namespace), runAt, raw); err != nil {
+			return nil, err
+		}
+		scheduled := fromGocraft(job)
+		scheduled.RunAt = runAt
+		jobs = append(jobs, scheduled)
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (b *GocraftBackend) Register(jobName string, options JobOptions, handler Handler) {
+	workOptions := work.JobOptions{
+		Priority:       options.Priority,
+		MaxFails:       options.MaxFails,
+		MaxConcurrency: options.MaxConcurrency,
+		SkipDead:       false,
+	}
+	if options.Backoff != nil {
+		workOptions.Backoff = func(job *work.Job) int64 {
+			return options.Backoff(fromGocraft(job))
+		}
+	}
+
+	b.pool.JobWithOptions(jobName, workOptions, func(job *work.Job) error {
+		return handler(fromGocraft(job))
+	})
+	b.names = append(b.names, jobName)
+}
+
+func (b *GocraftBackend) Start() {
+	b.pool.Start()
+}
+
+// Drain stops the pool and waits for the running jobs until ctx is done. Jobs still running then are
+// logged and moved from the in progress list of the pool back to their queue, for another worker to run
+// them, see requeueUnfinished. Should that fail they're left to the gocraft dead pool reaper of another
+// worker, which requeues them once the pool stopped heartbeating with the process.
+func (b *GocraftBackend) Drain(ctx context.Context) []*Job {
+	unfinished := drainPool(ctx, b.pool, b.jobs)
+	if len(unfinished) == 0 {
+		return nil
+	}
+
+	jobs := make([]*Job, 0, len(unfinished))
+	for _, job := range unfinished {
+		jobs = append(jobs, fromGocraft(job))
+	}
+	logUnfinished(jobs)
+	requeued, err := b.requeueUnfinished()
+	if err != nil {
+		log.Error("unable to requeue unfinished jobs, leaving them to the dead pool reaper", zap.Error(err))
+	} else {
+		log.Info("Requeued unfinished jobs", zap.Int("count", requeued))
+	}
+	return jobs
+}
+
+func (b *GocraftBackend) Depth(jobName string) (int64, error) {
+	conn := b.redis.Get()
+	defer conn.Close()
+	return redis.Int64(conn.Do("LLEN", gocraftJobsKey(b.namespace, jobName)))
+}
+
+// gocraftNamespacePrefix and the gocraft*Key functions mirror redisNamespacePrefix and the redisKey*
+// functions of gocraft/work, which aren't exported, for the keys jobs are pushed to without going
+// through its enqueuer or requeued from. They have to follow gocraft's key layout when it's upgraded.
+func gocraftNamespacePrefix(namespace string) string {
+	if namespace != "" && !strings.HasSuffix(namespace, ":") {
+		namespace += ":"
+	}
+	return namespace
+}
+
+// gocraftJobsKey is the list the jobs named jobName wait in to be run.
+func gocraftJobsKey(namespace, jobName string) string {
+	return gocraftNamespacePrefix(namespace) + "jobs:" + jobName
+}
+
+// gocraftScheduledKey is the sorted set the scheduled jobs wait in, scored by when they're due.
+func gocraftScheduledKey(namespace string) string {
+	return gocraftNamespacePrefix(namespace) + "scheduled"
+}
+
+// gocraftKnownJobsKey is the set of the names of the jobs ever enqueued.
+func gocraftKnownJobsKey(namespace string) string {
+	return gocraftNamespacePrefix(namespace) + "known_jobs"
+}
+
+// gocraftInProgressKey is the list the jobs named jobName run by the pool poolID are kept in meanwhile.
+func gocraftInProgressKey(namespace, poolID, jobName string) string {
+	return gocraftJobsKey(namespace, jobName) + ":" + poolID + ":inprogress"
+}
+
+// gocraftLockKey and gocraftLockInfoKey count the running jobs named jobName, overall and by pool, for
+// their max concurrency.
+func gocraftLockKey(namespace, jobName string) string {
+	return gocraftJobsKey(namespace, jobName) + ":lock"
+}
+
+func gocraftLockInfoKey(namespace, jobName string) string {
+	return gocraftJobsKey(namespace, jobName) + ":lock_info"
+}
+
+// gocraftPoolID returns the ID of the pool, which gocraft doesn't export either.
+func gocraftPoolID(pool *work.WorkerPool) string {
+	return reflect.ValueOf(pool).Elem().FieldByName("workerPoolID").String()
+}
+
+func (b *GocraftBackend) ScheduledJobs(page uint) ([]*Job, int64, error) {
+	scheduled, count, err := b.client.ScheduledJobs(page)
+	if err != nil {
+		return nil, 0, err
+	}
+	jobs := make([]*Job, 0, len(scheduled))
+	for _, s := range scheduled {
+		job := fromGocraft(s.Job)
+		job.RunAt = s.RunAt
+		jobs = append(jobs, job)
+	}
+	return jobs, count, nil
+}
+
+func (b *GocraftBackend) RetryJobs(page uint) ([]*Job, int64, error) {
+	retries, count, err := b.client.RetryJobs(page)
+	if err != nil {
+		return nil, 0, err
+	}
+	jobs := make([]*Job, 0, len(retries))
+	for _, r := range retries {
+		job := fromGocraft(r.Job)
+		job.RunAt = r.RetryAt
+		jobs = append(jobs, job)
+	}
+	return jobs, count, nil
+}
+
+func (b *GocraftBackend) DeadJobs(page uint) ([]*Job, int64, error) {
+	dead, count, err := b.client.DeadJobs(page)
+	if err != nil {
+		return nil, 0, err
+	}
+	jobs := make([]*Job, 0, len(dead))
+	for _, d := range dead {
+		job := fromGocraft(d.Job)
+		job.DiedAt = d.DiedAt
+		jobs = append(jobs, job)
+	}
+	return jobs, count, nil
+}
+
+func (b *GocraftBackend) RetryDeadJob(job *Job) error {
+	return b.client.RetryDeadJob(job.DiedAt, job.ID)
+}
+
+func fromGocraft(job *work.Job) *Job {
+	return &Job{
+		ID:         job.ID,
+		Name:       job.Name,
+		Args:       job.Args,
+		EnqueuedAt: job.EnqueuedAt,
+		Fails:      job.Fails,
+		LastErr:    job.LastErr,
+		FailedAt:   job.FailedAt,
+	}
+}
@@ -0,0 +1,219 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"swilly-delivery-service/internal/pkg/log"
+	"sync"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+)
+
+const (
+	// requeuedRunningTTL bounds how long the copy of a requeued job waits for the process that drained it,
+	// which may still finish it, before that process is presumed dead
+	requeuedRunningTTL = time.Minute
+	// requeuedDoneTTL is how long the copy of a requeued job finished by the process that drained it is
+	// skipped, covering the time it waits in the queue
+	requeuedDoneTTL      = 24 * time.Hour
+	requeuedPollInterval = 100 * time.Millisecond
+
+	requeuedRunning = "running"
+	requeuedDone    = "done"
+)
+
+// gocraftRequeuedKey holds whether the process that requeued the job with id is still running it or
+// finished it. It's not a gocraft key but lives in the namespace of the pool.
+func gocraftRequeuedKey(namespace, id string) string {
+	return gocraftNamespacePrefix(namespace) + "requeued:" + id
+}
+
+// inFlightJobs keeps track of the jobs being run by the pool, to report and requeue those that could not
+// finish before shutting down.
+type inFlightJobs struct {
+	mutex    sync.Mutex
+	jobs     map[string]*work.Job
+	requeued map[string]bool
+}
+
+func newInFlightJobs() *inFlightJobs {
+	return &inFlightJobs{jobs: make(map[string]*work.Job), requeued: make(map[string]bool)}
+}
+
+// track is a gocraft middleware recording the job while it runs.
+func (j *inFlightJobs) track(job *work.Job, next work.NextMiddlewareFunc) error {
+	_, err := j.run(job, next)
+	return err
+}
+
+// run runs the job, recording it meanwhile, and tells whether it was requeued before it finished.
+func (j *inFlightJobs) run(job *work.Job, next work.NextMiddlewareFunc) (requeued bool, err error) {
+	j.mutex.Lock()
+	j.jobs[job.ID] = job
+	j.mutex.Unlock()
+
+	defer func() {
+		j.mutex.Lock()
+		delete(j.jobs, job.ID)
+		requeued = j.requeued[job.ID]
+		delete(j.requeued, job.ID)
+		j.mutex.Unlock()
+	}()
+	return false, next()
+}
+
+// list returns the jobs still running, oldest first.
+func (j *inFlightJobs) list() []*work.Job {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	jobs := make([]*work.Job, 0, len(j.jobs))
+	for _, job := range j.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].EnqueuedAt < jobs[b].EnqueuedAt })
+	return jobs
+}
+
+// stopper is the part of the gocraft worker pool used to stop it.
+type stopper interface {
+	Stop()
+}
+
+// drainPool stops the pool from fetching new jobs and waits for the running ones to finish until ctx is
+// done. It returns the jobs that did not finish in time.
+func drainPool(ctx context.Context, pool stopper, jobs *inFlightJobs) []*work.Job {
+	stopped := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return jobs.list()
+	}
+}
+
+// requeueUnfinished moves the jobs still running from the in progress lists of the pool back to the
+// front of their queue, the way the gocraft dead pool reaper does once the pool is dead, and returns
+// how many were. A requeued job still runs in this process until it exits, so it's marked requeued
+// for its copy to wait on, see awaitRequeued, and handed over once finished, see handOver. It's done
+// under the lock of the running jobs, so that a job is either requeued before it finishes or not at all.
+func (b *GocraftBackend) requeueUnfinished() (int, error) {
+	b.jobs.mutex.Lock()
+	defer b.jobs.mutex.Unlock()
+
+	conn := b.redis.Get()
+	defer conn.Close()
+
+	type inProgressJob struct {
+		name, id string
+		raw      []byte
+	}
+	poolID := gocraftPoolID(b.pool)
+	var unfinished []inProgressJob
+	for _, name := range b.names {
+		raws, err := redis.ByteSlices(conn.Do("LRANGE", gocraftInProgressKey(b.namespace, poolID, name), 0, -1))
+		if err != nil {
+			return 0, err
+		}
+		for _, raw := range raws {
+			var job work.Job
+			if err := json.Unmarshal(raw, &job); err != nil {
+				continue
+			}
+			if _, running := b.jobs.jobs[job.ID]; running {
+				unfinished = append(unfinished, inProgressJob{name: name, id: job.ID, raw: raw})
+			}
+		}
+	}
+	if len(unfinished) == 0 {
+		return 0, nil
+	}
+
+	if err := conn.Send("MULTI"); err != nil {
+		return 0, err
+	}
+	for _, job := range unfinished {
+		for _, command := range [][]interface{}{
+			{"SET", gocraftRequeuedKey(b.namespace, job.id), requeuedRunning, "PX", requeuedRunningTTL.Milliseconds()},
+			{"LREM", gocraftInProgressKey(b.namespace, poolID, job.name), 1, job.raw},
+			{"RPUSH", gocraftJobsKey(b.namespace, job.name), job.raw},
+			{"DECR", gocraftLockKey(b.namespace, job.name)},
+			{"HINCRBY", gocraftLockInfoKey(b.namespace, job.name), poolID, -1},
+		} {
+			if err := conn.Send(command[0].(string), command[1:]...); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if _, err := conn.Do("EXEC"); err != nil {
+		return 0, err
+	}
+	for _, job := range unfinished {
+		b.jobs.requeued[job.id] = true
+	}
+	return len(unfinished), nil
+}
+
+// runJob is the gocraft middleware of the pool. It runs the job once its requeued copy, if it's one, is
+// due, and hands the job over when it's the original of a copy requeued meanwhile.
+func (b *GocraftBackend) runJob(job *work.Job, next work.NextMiddlewareFunc) error {
+	due, err := b.awaitRequeued(job)
+	if err != nil || !due {
+		return err
+	}
+	requeued, err := b.jobs.run(job, next)
+	if requeued {
+		return b.handOver(job, err)
+	}
+	return err
+}
+
+// awaitRequeued waits while the process that requeued the job may still be running it, and tells
+// whether the job should run: not if that process finished it after all.
+func (b *GocraftBackend) awaitRequeued(job *work.Job) (bool, error) {
+	conn := b.redis.Get()
+	defer conn.Close()
+
+	for {
+		state, err := redis.String(conn.Do("GET", gocraftRequeuedKey(b.namespace, job.ID)))
+		switch {
+		case err == redis.ErrNil:
+			return true, nil
+		case err != nil:
+			return false, err
+		case state == requeuedDone:
+			log.Info("Skipping requeued job finished by the process that requeued it", zap.String("jobID", job.ID), zap.String("jobName", job.Name))
+			return false, nil
+		}
+		time.Sleep(requeuedPollInterval)
+	}
+}
+
+// handOver records how a job requeued before it finished ended up, for its copy: skipped if it succeeded,
+// run otherwise. The copy is the retry of a failed job, which is not retried by the pool on top of it.
+func (b *GocraftBackend) handOver(job *work.Job, jobErr error) error {
+	conn := b.redis.Get()
+	defer conn.Close()
+
+	key := gocraftRequeuedKey(b.namespace, job.ID)
+	var err error
+	if jobErr == nil {
+		_, err = conn.Do("SET", key, requeuedDone, "PX", requeuedDoneTTL.Milliseconds())
+	} else {
+		log.Error("Requeued job failed, leaving it to its copy", zap.String("jobID", job.ID), zap.String("jobName", job.Name), zap.Error(jobErr))
+		_, err = conn.Do("DEL", key)
+	}
+	if err != nil {
+		log.Error("unable to hand over requeued job", zap.String("jobID", job.ID), zap.String("jobName", job.Name), zap.Error(err))
+	}
+	return nil
+}
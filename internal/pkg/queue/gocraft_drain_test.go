@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gocraft/work"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blockingPool struct {
+	release chan struct{}
+}
+
+func (p *blockingPool) Stop() {
+	<-p.release
+}
+
+func TestDrainPool_WaitsForRunningJobs(t *testing.T) {
+	pool := &blockingPool{release: make(chan struct{})}
+	close(pool.release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.Empty(t, drainPool(ctx, pool, newInFlightJobs()))
+}
+
+func TestDrainPool_ReturnsUnfinishedJobs(t *testing.T) {
+	pool := &blockingPool{release: make(chan struct{})}
+	defer close(pool.release)
+
+	jobs := newInFlightJobs()
+	running := make(chan struct{})
+	job := &work.Job{ID: "1", Name: "send_message"}
+	go jobs.track(job, func() error {
+		close(running)
+		<-pool.release
+		return nil
+	})
+	<-running
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	unfinished := drainPool(ctx, pool, jobs)
+	require.Len(t, unfinished, 1)
+	assert.Equal(t, "1", unfinished[0].ID)
+}
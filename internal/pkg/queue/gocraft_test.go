@@ -0,0 +1,216 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gocraft/work"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGocraftBackend(t *testing.T) *GocraftBackend {
+	server := miniredis.RunT(t)
+	return NewGocraftBackend(&redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}, "delivery", 2)
+}
+
+func TestGocraftBackend_RunsEnqueuedJobs(t *testing.T) {
+	backend := newTestGocraftBackend(t)
+	received := make(chan string, 1)
+	backend.Register("send_message", JobOptions{}, func(job *Job) error {
+		userID := job.ArgString("userID")
+		received <- userID
+		return job.ArgError()
+	})
+	backend.Start()
+	defer backend.Drain(context.Background())
+
+	_, err := backend.Enqueue("send_message", map[string]interface{}{"userID": "42"})
+	require.NoError(t, err)
+
+	select {
+	case userID := <-received:
+		assert.Equal(t, "42", userID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("job was not run")
+	}
+}
+
+func TestGocraftBackend_ScheduledJobs(t *testing.T) {
+	backend := newTestGocraftBackend(t)
+
+	job, err := backend.EnqueueIn("send_message", 60, map[string]interface{}{"userID": "42"})
+	require.NoError(t, err)
+	assert.Equal(t, job.EnqueuedAt+60, job.RunAt)
+
+	scheduled, count, err := backend.ScheduledJobs(1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	require.Len(t, scheduled, 1)
+	assert.Equal(t, job.ID, scheduled[0].ID)
+	assert.Equal(t, job.RunAt, scheduled[0].RunAt)
+	assert.Equal(t, "42", scheduled[0].Args["userID"])
+}
+
+func TestGocraftBackend_EnqueueBatchIn(t *testing.T) {
+	backend := newTestGocraftBackend(t)
+
+	jobs, err := backend.EnqueueBatchIn("send_message", 60, []map[string]interface{}{{"userID": "1"}, {"userID": "2"}})
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, jobs[0].EnqueuedAt+60, jobs[0].RunAt)
+
+	// Listed by the gocraft client among the scheduled jobs, not waiting in the queue
+	scheduled, count, err := backend.ScheduledJobs(1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	require.Len(t, scheduled, 2)
+	assert.ElementsMatch(t, []string{jobs[0].ID, jobs[1].ID}, []string{scheduled[0].ID, scheduled[1].ID})
+	assert.Equal(t, jobs[0].RunAt, scheduled[0].RunAt)
+	depth, err := backend.Depth("send_message")
+	require.NoError(t, err)
+	assert.Zero(t, depth)
+}
+
+func TestGocraftBackend_EnqueueBatch(t *testing.T) {
+	backend := newTestGocraftBackend(t)
+	received := make(chan string, 3)
+	backend.Register("send_message", JobOptions{}, func(job *Job) error {
+		received <- job.ArgString("userID")
+		return job.ArgError()
+	})
+
+	jobs, err := backend.EnqueueBatch("send_message", []map[string]interface{}{
+		{"userID": "1"}, {"userID": "2"}, {"userID": "3"},
+	})
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+	assert.NotEqual(t, jobs[0].ID, jobs[1].ID)
+	depth, err := backend.Depth("send_message")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, depth)
+
+	backend.Start()
+	defer backend.Drain(context.Background())
+	var userIDs []string
+	for len(userIDs) < 3 {
+		select {
+		case userID := <-received:
+			userIDs = append(userIDs, userID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("jobs were not run, got %v", userIDs)
+		}
+	}
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, userIDs)
+}
+
+func TestGocraftBackend_EnqueueBatchFollowsGocraftKeys(t *testing.T) {
+	server := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+	for _, namespace := range []string{"delivery", "delivery:"} {
+		t.Run(namespace, func(t *testing.T) {
+			_, err := NewGocraftBackend(pool, namespace, 1).EnqueueBatch("send_message", []map[string]interface{}{{"userID": "42"}})
+			require.NoError(t, err)
+
+			// Picked up by a plain gocraft worker pool, knowing nothing of the backend
+			received := make(chan string, 1)
+			workerPool := work.NewWorkerPool(jobContext{}, 1, namespace, pool)
+			workerPool.Job("send_message", func(job *work.Job) error {
+				received <- job.ArgString("userID")
+				return job.ArgError()
+			})
+			workerPool.Start()
+			defer workerPool.Stop()
+
+			select {
+			case userID := <-received:
+				assert.Equal(t, "42", userID)
+			case <-time.After(5 * time.Second):
+				t.Fatal("batched job was not run")
+			}
+			// Listed by gocraft among the known jobs
+			queues, err := work.NewClient(namespace, pool).Queues()
+			require.NoError(t, err)
+			require.Len(t, queues, 1)
+			assert.Equal(t, "send_message", queues[0].JobName)
+		})
+	}
+}
+
+func TestGocraftBackend_DrainRequeuesUnfinishedJobs(t *testing.T) {
+	for name, originalErr := range map[string]error{"finished": nil, "failed": errors.New("webhook timed out")} {
+		t.Run(name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			pool := &redis.Pool{
+				Dial: func() (redis.Conn, error) {
+					return redis.Dial("tcp", server.Addr())
+				},
+			}
+
+			// The draining backend is still running the job when it gives up on it
+			draining := NewGocraftBackend(pool, "delivery", 1)
+			started, release := make(chan struct{}), make(chan struct{})
+			draining.Register("send_message", JobOptions{}, func(*Job) error {
+				close(started)
+				<-release
+				return originalErr
+			})
+			draining.Start()
+			job, err := draining.Enqueue("send_message", map[string]interface{}{"userID": "42"})
+			require.NoError(t, err)
+			<-started
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			unfinished := draining.Drain(ctx)
+			require.Len(t, unfinished, 1)
+			depth, err := draining.Depth("send_message")
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, depth)
+
+			// Its copy waits for the job to finish, and only runs if it failed
+			received := make(chan string, 1)
+			other := NewGocraftBackend(pool, "delivery", 1)
+			other.Register("send_message", JobOptions{}, func(job *Job) error {
+				received <- job.ID
+				return nil
+			})
+			other.Start()
+			defer other.Drain(context.Background())
+			time.Sleep(200 * time.Millisecond)
+			assert.Empty(t, received)
+			close(release)
+
+			if originalErr == nil {
+				require.Eventually(t, func() bool {
+					depth, err := other.Depth("send_message")
+					return err == nil && depth == 0
+				}, 5*time.Second, 10*time.Millisecond)
+				time.Sleep(200 * time.Millisecond)
+				assert.Empty(t, received)
+			} else {
+				select {
+				case id := <-received:
+					assert.Equal(t, job.ID, id)
+				case <-time.After(5 * time.Second):
+					t.Fatal("requeued job was not run")
+				}
+			}
+			retries, count, err := other.RetryJobs(1)
+			require.NoError(t, err)
+			assert.Zero(t, count, retries)
+		})
+	}
+}
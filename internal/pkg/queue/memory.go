@@ -0,0 +1,415 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"sort"
+	"swilly-delivery-service/internal/pkg/log"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultMaxFails is the number of fails after which a job is dead unless its options say otherwise,
+// the same as gocraft.
+const defaultMaxFails = 4
+
+// memoryPollInterval is how often idle workers look for due scheduled and retried jobs.
+const memoryPollInterval = 100 * time.Millisecond
+
+var errDeadJobNotFound = errors.New("dead job not found")
+
+// memoryJobType is a registered job name.
+type memoryJobType struct {
+	name    string
+	options JobOptions
+	handler Handler
+	running uint
+}
+
+// MemoryBackend keeps the jobs in memory and runs them in the process, for local development and tests
+// without redis. Jobs not run yet are lost when the process exits.
+type MemoryBackend struct {
+	concurrency uint
+	now         func() time.Time
+	// sample returns a random number in [0, n), to pick the name of the next job to run
+	sample func(n int64) int64
+
+	mutex     sync.Mutex
+	types     map[string]*memoryJobType
+	queues    map[string][]*Job
+	scheduled []*Job
+	retries   []*Job
+	dead      []*Job
+	running   map[string]*Job
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	workers  sync.WaitGroup
+}
+
+func NewMemoryBackend(concurrency uint) *MemoryBackend {
+	return &MemoryBackend{
+		concurrency: concurrency,
+		now:         time.Now,
+		sample:      mathrand.Int63n,
+		types:       make(map[string]*memoryJobType),
+		queues:      make(map[string][]*Job),
+		running:     make(map[string]*Job),
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+	}
+}
+
+func (b *MemoryBackend) Enqueue(jobName string, args map[string]interface{}) (*Job, error) {
+	job, err := b.newJob(jobName, args)
+	if err != nil {
+		return nil, err
+	}
+	enqueued := *job
+
+	b.mutex.Lock()
+	b.queues[jobName] = append(b.queues[jobName], job)
+	b.mutex.Unlock()
+	b.signal()
+	return &enqueued, nil
+}
+
+func (b *MemoryBackend) EnqueueBatch(jobName string, args []map[string]interface{}) ([]*Job, error) {
+	jobs := make([]*Job, 0, len(args))
+	for _, jobArgs := range args {
+		job, err := b.newJob(jobName, jobArgs)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	enqueued := make([]*Job, 0, len(jobs))
+	for _, job := range jobs {
+		copied := *job
+		enqueued = append(enqueued, &copied)
+	}
+
+	b.mutex.Lock()
+	b.queues[jobName] = append(b.queues[jobName], jobs...)
+	b.mutex.Unlock()
+	b.signal()
+	return enqueued, nil
+}
+
+func (b *MemoryBackend) EnqueueIn(jobName string, secondsFromNow int64, args map[string]interface{}) (*Job, error) {
+	job, err := b.newJob(jobName, args)
+	if err != nil {
+		return nil, err
+	}
+	job.RunAt = job.EnqueuedAt + secondsFromNow
+	scheduled := *job
+
+	b.mutex.Lock()
+	b.scheduled = append(b.scheduled, job)
+	b.mutex.Unlock()
+	return &scheduled, nil
+}
+
+func (b *MemoryBackend) EnqueueBatchIn(jobName string, secondsFromNow int64, args []map[string]interface{}) ([]*Job, error) {
+	jobs := make([]*Job, 0, len(args))
+	scheduled := make([]*Job, 0, len(args))
+	for _, jobArgs := range args {
+		job, err := b.newJob(jobName, jobArgs)
+		if err != nil {
+			return nil, err
+		}
+		job.RunAt = job.EnqueuedAt + secondsFromNow
+		copied := *job
+		jobs = append(jobs, job)
+		scheduled = append(scheduled, &copied)
+	}
+
+	b.mutex.Lock()
+	b.scheduled = append(b.scheduled, jobs...)
+	b.mutex.Unlock()
+	return scheduled, nil
+}
+
+func (b *MemoryBackend) newJob(jobName string, args map[string]interface{}) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	return &Job{ID: id, Name: jobName, Args: args, EnqueuedAt: b.now().Unix()}, nil
+}
+
+// signal wakes up an idle worker.
+func (b *MemoryBackend) signal() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (b *MemoryBackend) Register(jobName string, options JobOptions, handler Handler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.types[jobName] = &memoryJobType{name: jobName, options: options, handler: handler}
+}
+
+func (b *MemoryBackend) Start() {
+	for i := uint(0); i < b.concurrency; i++ {
+		b.workers.Add(1)
+		go b.work()
+	}
+}
+
+func (b *MemoryBackend) work() {
+	defer b.workers.Done()
+
+	for {
+		select {
+		case <-b.stop:
+			return
+		default:
+		}
+
+		job, jobType := b.fetch()
+		if job == nil {
+			select {
+			case <-b.stop:
+				return
+			case <-b.wake:
+			case <-time.After(memoryPollInterval):
+			}
+			continue
+		}
+		b.run(job, jobType)
+	}
+}
+
+// fetch takes the next job to run. Like gocraft, the name it's taken from is sampled among those with
+// jobs waiting, weighted by priority, so that names of low priority are not starved by busier ones.
+func (b *MemoryBackend) fetch() (*Job, *memoryJobType) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now().Unix()
+	b.scheduled = b.enqueueDue(b.scheduled, now)
+	b.retries = b.enqueueDue(b.retries, now)
+
+	var types []*memoryJobType
+	var total int64
+	for _, jobType := range b.types {
+		if len(b.queues[jobType.name]) == 0 || (jobType.options.MaxConcurrency > 0 && jobType.running >= jobType.options.MaxConcurrency) {
+			continue
+		}
+		types = append(types, jobType)
+		total += jobWeight(jobType)
+	}
+	if len(types) == 0 {
+		return nil, nil
+	}
+	// Sorted for a given sample to always pick the same name
+	sort.Slice(types, func(i, j int) bool { return types[i].name < types[j].name })
+
+	jobType := types[len(types)-1]
+	sample := b.sample(total)
+	for _, candidate := range types {
+		if sample < jobWeight(candidate) {
+			jobType = candidate
+			break
+		}
+		sample -= jobWeight(candidate)
+	}
+
+	queue := b.queues[jobType.name]
+	job := queue[0]
+	b.queues[jobType.name] = queue[1:]
+	jobType.running++
+	b.running[job.ID] = job
+	return job, jobType
+}
+
+// jobWeight is the weight of the jobs of a name when sampling the next one to run, their priority or 1 by
+// default as with gocraft.
+func jobWeight(jobType *memoryJobType) int64 {
+	if jobType.options.Priority == 0 {
+		return 1
+	}
+	return int64(jobType.options.Priority)
+}
+
+// enqueueDue moves the jobs due by now to their queue and returns the others.
+func (b *MemoryBackend) enqueueDue(jobs []*Job, now int64) []*Job {
+	waiting := jobs[:0]
+	for _, job := range jobs {
+		if job.RunAt <= now {
+			b.queues[job.Name] = append(b.queues[job.Name], job)
+			continue
+		}
+		waiting = append(waiting, job)
+	}
+	return waiting
+}
+
+func (b *MemoryBackend) run(job *Job, jobType *memoryJobType) {
+	job.argError = nil
+	err := runHandler(jobType.handler, job)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	jobType.running--
+	delete(b.running, job.ID)
+	if err == nil {
+		return
+	}
+
+	now := b.now().Unix()
+	job.Fails++
+	job.LastErr = err.Error()
+	job.FailedAt = now
+
+	maxFails := int64(jobType.options.MaxFails)
+	if maxFails == 0 {
+		maxFails = defaultMaxFails
+	}
+	if job.Fails >= maxFails {
+		log.Error("Job failed too many times, giving up", zap.String("jobID", job.ID), zap.String("jobName", job.Name), zap.Error(err))
+		job.DiedAt = now
+		b.dead = append(b.dead, job)
+		return
+	}
+
+	backoff := defaultBackoff
+	if jobType.options.Backoff != nil {
+		backoff = jobType.options.Backoff
+	}
+	job.RunAt = now + backoff(job)
+	b.retries = append(b.retries, job)
+}
+
+// runHandler runs the job, turning a panic into an error as gocraft does.
+func runHandler(handler Handler, job *Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	return handler(job)
+}
+
+// defaultBackoff is the gocraft backoff, growing with the fourth power of the number of fails.
+func defaultBackoff(job *Job) int64 {
+	fails := job.Fails
+	return fails*fails*fails*fails + 15 + mathrand.Int63n(30)*(fails+1)
+}
+
+// Drain stops the workers and waits for the running jobs until ctx is done. Unlike with redis, jobs
+// still running or waiting are lost with the process, so they are only logged.
+func (b *MemoryBackend) Drain(ctx context.Context) []*Job {
+	b.stopOnce.Do(func() { close(b.stop) })
+
+	stopped := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(stopped)
+	}()
+
+	var unfinished []*Job
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		unfinished = b.unfinished()
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	logUnfinished(unfinished)
+	waiting := len(b.scheduled) + len(b.retries)
+	for _, queue := range b.queues {
+		waiting += len(queue)
+	}
+	if waiting > 0 {
+		log.Error("Dropping jobs of the in-memory queue on shutdown", zap.Int("count", waiting))
+	}
+	return unfinished
+}
+
+// unfinished returns the jobs still running, oldest first.
+func (b *MemoryBackend) unfinished() []*Job {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	jobs := make([]*Job, 0, len(b.running))
+	for _, job := range b.running {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].EnqueuedAt < jobs[j].EnqueuedAt })
+	return jobs
+}
+
+func (b *MemoryBackend) Depth(jobName string) (int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return int64(len(b.queues[jobName])), nil
+}
+
+func (b *MemoryBackend) ScheduledJobs(page uint) ([]*Job, int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return jobsPage(b.scheduled, page, func(job *Job) int64 { return job.RunAt }), int64(len(b.scheduled)), nil
+}
+
+func (b *MemoryBackend) RetryJobs(page uint) ([]*Job, int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return jobsPage(b.retries, page, func(job *Job) int64 { return job.RunAt }), int64(len(b.retries)), nil
+}
+
+func (b *MemoryBackend) DeadJobs(page uint) ([]*Job, int64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return jobsPage(b.dead, page, func(job *Job) int64 { return job.DiedAt }), int64(len(b.dead)), nil
+}
+
+// jobsPage sorts copies of the jobs by score, like the redis sorted sets of gocraft, and returns the page.
+func jobsPage(jobs []*Job, page uint, score func(*Job) int64) []*Job {
+	sorted := make([]*Job, 0, len(jobs))
+	for _, job := range jobs {
+		copied := *job
+		sorted = append(sorted, &copied)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return score(sorted[i]) < score(sorted[j]) })
+
+	if page < 1 {
+		page = 1
+	}
+	start := int(page-1) * pageSize
+	if start >= len(sorted) {
+		return []*Job{}
+	}
+	end := start + pageSize
+	if end > len(sorted) {
+		end = len(sorted)
+	}
+	return sorted[start:end]
+}
+
+func (b *MemoryBackend) RetryDeadJob(job *Job) error {
+	b.mutex.Lock()
+	for i, dead := range b.dead {
+		if dead.ID != job.ID {
+			continue
+		}
+		b.dead = append(b.dead[:i], b.dead[i+1:]...)
+		dead.Fails, dead.LastErr, dead.FailedAt, dead.DiedAt = 0, "", 0, 0
+		dead.EnqueuedAt = b.now().Unix()
+		b.queues[dead.Name] = append(b.queues[dead.Name], dead)
+		b.mutex.Unlock()
+		b.signal()
+		return nil
+	}
+	b.mutex.Unlock()
+	return errDeadJobNotFound
+}
@@ -0,0 +1,246 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a settable time for the scheduled and retried jobs of the memory backend.
+type clock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestMemoryBackend(t *testing.T) (*MemoryBackend, *clock) {
+	clock := &clock{now: time.Unix(1700000000, 0)}
+	backend := NewMemoryBackend(2)
+	backend.now = clock.Now
+	t.Cleanup(func() { backend.Drain(context.Background()) })
+	return backend, clock
+}
+
+func TestMemoryBackend_RunsEnqueuedJobs(t *testing.T) {
+	backend, _ := newTestMemoryBackend(t)
+	received := make(chan string, 1)
+	backend.Register("send_message", JobOptions{}, func(job *Job) error {
+		userID := job.ArgString("userID")
+		received <- userID
+		return job.ArgError()
+	})
+	backend.Start()
+
+	_, err := backend.Enqueue("send_message", map[string]interface{}{"userID": "42"})
+	require.NoError(t, err)
+
+	select {
+	case userID := <-received:
+		assert.Equal(t, "42", userID)
+	case <-time.After(time.Second):
+		t.Fatal("job was not run")
+	}
+}
+
+func TestMemoryBackend_EnqueueBatch(t *testing.T) {
+	backend, _ := newTestMemoryBackend(t)
+	received := make(chan string, 3)
+	backend.Register("send_message", JobOptions{}, func(job *Job) error {
+		received <- job.ArgString("userID")
+		return nil
+	})
+	backend.Start()
+
+	jobs, err := backend.EnqueueBatch("send_message", []map[string]interface{}{
+		{"userID": "1"}, {"userID": "2"}, {"userID": "3"},
+	})
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+
+	var userIDs []string
+	for len(userIDs) < 3 {
+		select {
+		case userID := <-received:
+			userIDs = append(userIDs, userID)
+		case <-time.After(time.Second):
+			t.Fatalf("jobs were not run, got %v", userIDs)
+		}
+	}
+	assert.ElementsMatch(t, []string{"1", "2", "3"}, userIDs)
+}
+
+func TestMemoryBackend_RunsScheduledJobsWhenDue(t *testing.T) {
+	backend, clock := newTestMemoryBackend(t)
+	var runs atomic.Int32
+	backend.Register("send_message", JobOptions{}, func(*Job) error {
+		runs.Add(1)
+		return nil
+	})
+	backend.Start()
+
+	job, err := backend.EnqueueIn("send_message", 60, map[string]interface{}{"userID": "42"})
+	require.NoError(t, err)
+	assert.Equal(t, job.EnqueuedAt+60, job.RunAt)
+
+	scheduled, count, err := backend.ScheduledJobs(1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	require.Len(t, scheduled, 1)
+	assert.Equal(t, job.ID, scheduled[0].ID)
+	time.Sleep(2 * memoryPollInterval)
+	assert.Zero(t, runs.Load())
+
+	clock.Add(time.Minute)
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 10*time.Millisecond)
+	_, count, err = backend.ScheduledJobs(1)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func TestMemoryBackend_EnqueueBatchIn(t *testing.T) {
+	backend, clock := newTestMemoryBackend(t)
+	var runs atomic.Int32
+	backend.Register("send_message", JobOptions{}, func(*Job) error {
+		runs.Add(1)
+		return nil
+	})
+	backend.Start()
+
+	jobs, err := backend.EnqueueBatchIn("send_message", 60, []map[string]interface{}{{"userID": "1"}, {"userID": "2"}})
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, jobs[0].EnqueuedAt+60, jobs[0].RunAt)
+	_, count, err := backend.ScheduledJobs(1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+
+	clock.Add(time.Minute)
+	require.Eventually(t, func() bool { return runs.Load() == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestMemoryBackend_RetriesThenKillsFailingJobs(t *testing.T) {
+	backend, clock := newTestMemoryBackend(t)
+	var runs atomic.Int32
+	backend.Register("send_message", JobOptions{MaxFails: 2, Backoff: func(*Job) int64 { return 10 }}, func(*Job) error {
+		runs.Add(1)
+		return errors.New("webhook unavailable")
+	})
+	backend.Start()
+
+	job, err := backend.Enqueue("send_message", map[string]interface{}{"userID": "42"})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, count, _ := backend.RetryJobs(1)
+		return count == 1
+	}, time.Second, 10*time.Millisecond)
+	retries, _, err := backend.RetryJobs(1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, retries[0].Fails)
+	assert.Equal(t, "webhook unavailable", retries[0].LastErr)
+	assert.Equal(t, clock.Now().Unix()+10, retries[0].RunAt)
+
+	clock.Add(10 * time.Second)
+	require.Eventually(t, func() bool {
+		_, count, _ := backend.DeadJobs(1)
+		return count == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 2, runs.Load())
+
+	dead, _, err := backend.DeadJobs(1)
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, dead[0].ID)
+	require.NoError(t, backend.RetryDeadJob(dead[0]))
+	require.Eventually(t, func() bool { return runs.Load() == 3 }, time.Second, 10*time.Millisecond)
+	assert.True(t, errors.Is(backend.RetryDeadJob(dead[0]), errDeadJobNotFound))
+}
+
+func TestMemoryBackend_HonoursMaxConcurrency(t *testing.T) {
+	backend, _ := newTestMemoryBackend(t)
+	release := make(chan struct{})
+	var running, maxRunning atomic.Int32
+	backend.Register("send_message", JobOptions{MaxConcurrency: 1}, func(*Job) error {
+		current := running.Add(1)
+		if current > maxRunning.Load() {
+			maxRunning.Store(current)
+		}
+		<-release
+		running.Add(-1)
+		return nil
+	})
+	backend.Start()
+
+	for i := 0; i < 3; i++ {
+		_, err := backend.Enqueue("send_message", map[string]interface{}{})
+		require.NoError(t, err)
+	}
+	time.Sleep(2 * memoryPollInterval)
+	depth, err := backend.Depth("send_message")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, depth)
+	close(release)
+	backend.Drain(context.Background())
+	assert.EqualValues(t, 1, maxRunning.Load())
+}
+
+func TestMemoryBackend_DrainReturnsUnfinishedJobs(t *testing.T) {
+	backend, _ := newTestMemoryBackend(t)
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	backend.Register("send_message", JobOptions{}, func(*Job) error {
+		close(started)
+		<-release
+		return nil
+	})
+	backend.Start()
+
+	job, err := backend.Enqueue("send_message", map[string]interface{}{"userID": "42"})
+	require.NoError(t, err)
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	unfinished := backend.Drain(ctx)
+	require.Len(t, unfinished, 1)
+	assert.Equal(t, job.ID, unfinished[0].ID)
+}
+
+func TestMemoryBackend_SamplesJobNamesByPriority(t *testing.T) {
+	backend, _ := newTestMemoryBackend(t)
+	backend.Register("low", JobOptions{Priority: 1}, func(*Job) error { return nil })
+	backend.Register("high", JobOptions{Priority: 3}, func(*Job) error { return nil })
+	for _, jobName := range []string{"low", "high"} {
+		_, err := backend.EnqueueBatch(jobName, []map[string]interface{}{{}, {}, {}, {}})
+		require.NoError(t, err)
+	}
+
+	// Names are sampled in order, high then low, out of a total weight of 4
+	var fetched []string
+	for _, sample := range []int64{0, 2, 3, 3} {
+		backend.sample = func(n int64) int64 {
+			assert.Equal(t, int64(4), n)
+			return sample
+		}
+		job, _ := backend.fetch()
+		fetched = append(fetched, job.Name)
+	}
+	assert.Equal(t, []string{"high", "high", "low", "low"}, fetched)
+}
@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"swilly-delivery-service/internal/pkg/log"
+
+	"go.uber.org/zap"
+)
+
+// pageSize is the number of jobs in a page of scheduled, retry or dead jobs.
+const pageSize = 20
+
+// Job is a unit of work enqueued by the server and run by the worker.
+type Job struct {
+	ID         string
+	Name       string
+	Args       map[string]interface{}
+	EnqueuedAt int64
+	// RunAt is when a scheduled job or the retry of a failed one is due, in unix seconds
+	RunAt    int64
+	Fails    int64
+	LastErr  string
+	FailedAt int64
+	// DiedAt is when the job was moved to the dead jobs after failing too many times, in unix seconds
+	DiedAt int64
+
+	argError error
+}
+
+// ArgString returns the string argument named key. A missing or mistyped argument returns "" and is
+// reported by ArgError.
+func (j *Job) ArgString(key string) string {
+	value, ok := j.Args[key]
+	if !ok {
+		j.setArgError(fmt.Errorf("job argument %s is missing", key))
+		return ""
+	}
+	s, ok := value.(string)
+	if !ok {
+		j.setArgError(fmt.Errorf("job argument %s is a %T, not a string", key, value))
+		return ""
+	}
+	return s
+}
+
+// ArgError returns the first error met by the Arg* functions.
+func (j *Job) ArgError() error {
+	return j.argError
+}
+
+func (j *Job) setArgError(err error) {
+	if j.argError == nil {
+		j.argError = err
+	}
+}
+
+// logUnfinished logs the jobs that did not finish before shutting down.
+func logUnfinished(jobs []*Job) {
+	for _, job := range jobs {
+		log.Error("Job did not finish before shutdown",
+			zap.String("jobID", job.ID),
+			zap.String("jobName", job.Name),
+			zap.Any("args", job.Args),
+		)
+	}
+}
+
+// newJobID returns a random job ID, in the format of gocraft's.
+func newJobID() (string, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Handler runs a job, which is retried when it returns an error.
+type Handler func(job *Job) error
+
+// JobOptions tune how the jobs of a name are run.
+type JobOptions struct {
+	// Priority weighs how often jobs of this name are fetched compared to others, from 1 to 100000
+	Priority uint
+	// MaxFails is the number of fails after which the job is dead, 0 for the backend's default
+	MaxFails uint
+	// MaxConcurrency caps the number of jobs of this name running at once, 0 for no cap
+	MaxConcurrency uint
+	// Backoff returns the number of seconds to wait before retrying the job, nil for the backend's default
+	Backoff func(job *Job) int64
+}
+
+// Backend enqueues jobs, runs them and keeps track of those waiting to be run.
+type Backend interface {
+	Enqueue(jobName string, args map[string]interface{}) (*Job, error)
+	// EnqueueBatch enqueues a job named jobName for each of args at once, all or none of them.
+	EnqueueBatch(jobName string, args []map[string]interface{}) ([]*Job, error)
+	// EnqueueIn schedules a job to be run secondsFromNow.
+	EnqueueIn(jobName string, secondsFromNow int64, args map[string]interface{}) (*Job, error)
+	// EnqueueBatchIn schedules a job named jobName for each of args to be run secondsFromNow at once, all or
+	// none of them.
+	EnqueueBatchIn(jobName string, secondsFromNow int64, args []map[string]interface{}) ([]*Job, error)
+
+	// Register has handler run the jobs named jobName once started. It must be called before Start.
+	Register(jobName string, options JobOptions, handler Handler)
+	// Start starts fetching and running the registered jobs in the background.
+	Start()
+	// Drain stops fetching jobs and waits for the running ones to finish until ctx is done. The jobs
+	// still running then are logged and returned, and requeued by backends whose queue outlives the process.
+	Drain(ctx context.Context) []*Job
+
+	// Depth returns the number of jobs named jobName waiting to be run, leaving out the scheduled and
+	// retried ones that are not due yet.
+	Depth(jobName string) (int64, error)
+	// ScheduledJobs, RetryJobs and DeadJobs list a page of the jobs waiting to be run, to be retried and
+	// given up on. Pages start at 1. The total number of jobs is returned along.
+	ScheduledJobs(page uint) ([]*Job, int64, error)
+	RetryJobs(page uint) ([]*Job, int64, error)
+	DeadJobs(page uint) ([]*Job, int64, error)
+	// RetryDeadJob puts a dead job back in its queue, clearing its fails.
+	RetryDeadJob(job *Job) error
+}
@@ -0,0 +1,129 @@
+package recipient
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// byteOrderMark is written at the start of files by some spreadsheet exports.
+const byteOrderMark = "\uFEFF"
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Validator tells whether a recipient ID read from a file is one messages can be sent to.
+type Validator interface {
+	// Validate returns an error telling why id is invalid, nil if it's valid. id is expected to be
+	// normalized.
+	Validate(id string) error
+}
+
+// Normalize strips what editors and exports add around recipient IDs: a byte order mark, surrounding
+// whitespace and the carriage return of CRLF line endings.
+func Normalize(id string) string {
+	return strings.TrimSpace(strings.TrimPrefix(id, byteOrderMark))
+}
+
+type intValidator struct {
+	min, max *big.Int
+}
+
+// NewIntValidator returns a Validator of integer IDs of any size between min and max included. An empty
+// bound leaves the range open on that side.
+func NewIntValidator(min, max string) (Validator, error) {
+	v := &intValidator{}
+	var err error
+	if v.min, err = parseBound(min); err != nil {
+		return nil, fmt.Errorf("invalid minimum: %w", err)
+	}
+	if v.max, err = parseBound(max); err != nil {
+		return nil, fmt.Errorf("invalid maximum: %w", err)
+	}
+	if v.min != nil && v.max != nil && v.min.Cmp(v.max) > 0 {
+		return nil, fmt.Errorf("minimum %s is above maximum %s", v.min, v.max)
+	}
+	return v, nil
+}
+
+func parseBound(bound string) (*big.Int, error) {
+	if bound == "" {
+		return nil, nil
+	}
+	n, ok := new(big.Int).SetString(bound, 10)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an integer", bound)
+	}
+	return n, nil
+}
+
+func (v *intValidator) Validate(id string) error {
+	n, ok := new(big.Int).SetString(id, 10)
+	if !ok {
+		return fmt.Errorf("%q is not an integer", id)
+	}
+	if v.min != nil && n.Cmp(v.min) < 0 {
+		return fmt.Errorf("%s is below %s", id, v.min)
+	}
+	if v.max != nil && n.Cmp(v.max) > 0 {
+		return fmt.Errorf("%s is above %s", id, v.max)
+	}
+	return nil
+}
+
+type uuidValidator struct{}
+
+// NewUUIDValidator returns a Validator of IDs that are UUIDs in their canonical, hyphenated form.
+func NewUUIDValidator() Validator {
+	return uuidValidator{}
+}
+
+func (uuidValidator) Validate(id string) error {
+	if !uuidPattern.MatchString(id) {
+		return fmt.Errorf("%q is not a UUID", id)
+	}
+	return nil
+}
+
+type regexValidator struct {
+	pattern *regexp.Regexp
+}
+
+// NewRegexValidator returns a Validator of IDs matching pattern as a whole.
+func NewRegexValidator(pattern string) (Validator, error) {
+	compiled, err := regexp.Compile(`^(?:` + pattern + `)$`)
+	if err != nil {
+		return nil, err
+	}
+	return &regexValidator{pattern: compiled}, nil
+}
+
+func (v *regexValidator) Validate(id string) error {
+	if !v.pattern.MatchString(id) {
+		return fmt.Errorf("%q does not match %s", id, v.pattern)
+	}
+	return nil
+}
+
+type prefixValidator struct {
+	prefixes []string
+}
+
+// NewPrefixValidator returns a Validator of IDs made of one of prefixes followed by at least one more
+// character, e.g. u_123 for the prefix u_.
+func NewPrefixValidator(prefixes []string) (Validator, error) {
+	if len(prefixes) == 0 {
+		return nil, errors.New("no prefix allowed")
+	}
+	return &prefixValidator{prefixes: prefixes}, nil
+}
+
+func (v *prefixValidator) Validate(id string) error {
+	for _, prefix := range v.prefixes {
+		if strings.HasPrefix(id, prefix) && len(id) > len(prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q does not start with one of %s", id, strings.Join(v.prefixes, ", "))
+}
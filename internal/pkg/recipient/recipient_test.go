@@ -0,0 +1,71 @@
+package recipient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "123", Normalize("\uFEFF123"))
+	assert.Equal(t, "123", Normalize(" 123\t\r"))
+	assert.Equal(t, "u_1 2", Normalize(" u_1 2 "))
+	assert.Equal(t, "", Normalize("\r"))
+}
+
+func TestIntValidator(t *testing.T) {
+	unbounded, err := NewIntValidator("", "")
+	require.NoError(t, err)
+	assert.NoError(t, unbounded.Validate("42"))
+	assert.NoError(t, unbounded.Validate("-42"))
+	assert.NoError(t, unbounded.Validate("123456789012345678901234567890"))
+	assert.Error(t, unbounded.Validate("4 2"))
+	assert.Error(t, unbounded.Validate("u_42"))
+	assert.Error(t, unbounded.Validate(""))
+
+	bounded, err := NewIntValidator("1", "99999999999999999999")
+	require.NoError(t, err)
+	assert.NoError(t, bounded.Validate("1"))
+	assert.NoError(t, bounded.Validate("99999999999999999999"))
+	assert.EqualError(t, bounded.Validate("0"), "0 is below 1")
+	assert.EqualError(t, bounded.Validate("100000000000000000000"), "100000000000000000000 is above 99999999999999999999")
+
+	_, err = NewIntValidator("ten", "")
+	assert.Error(t, err)
+	_, err = NewIntValidator("10", "1")
+	assert.Error(t, err)
+}
+
+func TestUUIDValidator(t *testing.T) {
+	validator := NewUUIDValidator()
+	assert.NoError(t, validator.Validate("0b5b9d6e-3c1f-4d0e-9a57-4f3c2e1d0a9b"))
+	assert.NoError(t, validator.Validate("0B5B9D6E-3C1F-4D0E-9A57-4F3C2E1D0A9B"))
+	assert.Error(t, validator.Validate("0b5b9d6e3c1f4d0e9a574f3c2e1d0a9b"))
+	assert.Error(t, validator.Validate("0b5b9d6e-3c1f-4d0e-9a57-4f3c2e1d0a9"))
+	assert.Error(t, validator.Validate("42"))
+}
+
+func TestRegexValidator(t *testing.T) {
+	validator, err := NewRegexValidator(`[a-z]{2}\d+`)
+	require.NoError(t, err)
+	assert.NoError(t, validator.Validate("ab123"))
+	// The pattern must match the whole ID
+	assert.Error(t, validator.Validate("xab123"))
+	assert.Error(t, validator.Validate("ab123x"))
+
+	_, err = NewRegexValidator(`[`)
+	assert.Error(t, err)
+}
+
+func TestPrefixValidator(t *testing.T) {
+	validator, err := NewPrefixValidator([]string{"u_", "guest_"})
+	require.NoError(t, err)
+	assert.NoError(t, validator.Validate("u_123"))
+	assert.NoError(t, validator.Validate("guest_abc"))
+	assert.Error(t, validator.Validate("u_"))
+	assert.EqualError(t, validator.Validate("x_123"), `"x_123" does not start with one of u_, guest_`)
+
+	_, err = NewPrefixValidator(nil)
+	assert.Error(t, err)
+}
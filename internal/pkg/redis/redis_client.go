@@ -1,20 +1,134 @@
 package redisclient
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
 	"swilly-delivery-service/config"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
 )
 
-func NewRedisPool() *redis.Pool {
+// Pool is a redis.Pool that stops following the sentinels once closed.
+type Pool struct {
+	*redis.Pool
+	// sentinel is nil unless in sentinel mode
+	sentinel *sentinel
+}
+
+// Close stops following the sentinels and closes the pool.
+func (p *Pool) Close() error {
+	if p.sentinel != nil {
+		p.sentinel.close()
+	}
+	return p.Pool.Close()
+}
+
+func NewRedisPool() (*Pool, error) {
 	redisConfig := config.AppConfig.StandaloneRedisConfig
-	return &redis.Pool{
+	options, err := dialOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	pool := &Pool{}
+	dial := func() (redis.Conn, error) {
+		return redis.Dial("tcp", redisConfig.RedisHost, options...)
+	}
+	testMaster := func(redis.Conn) error { return nil }
+	if config.AppConfig.RedisMode == config.RedisModeSentinel {
+		sentinelOptions, err := sentinelDialOptions()
+		if err != nil {
+			return nil, err
+		}
+		pool.sentinel = newSentinel(config.AppConfig.SentinelRedisConfig.Addrs, config.AppConfig.SentinelRedisConfig.MasterName, sentinelOptions...)
+		go pool.sentinel.watch()
+		dial = func() (redis.Conn, error) {
+			return pool.sentinel.dial(options...)
+		}
+		testMaster = pool.sentinel.testMaster
+	}
+
+	pool.Pool = &redis.Pool{
 		MaxActive:   redisConfig.RedisPoolSize,
 		MaxIdle:     redisConfig.RedisPoolSize,
 		IdleTimeout: redisConfig.RedisPoolTimeout,
 		Wait:        true,
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp", redisConfig.RedisHost)
+		Dial:        dial,
+		TestOnBorrow: func(conn redis.Conn, idleSince time.Time) error {
+			if err := testMaster(conn); err != nil {
+				return err
+			}
+			if time.Since(idleSince) < redisConfig.RedisHealthCheckInterval {
+				return nil
+			}
+			_, err := conn.Do("PING")
+			return err
 		},
 	}
+	return pool, nil
+}
+
+// sentinelDialOptions connects to the sentinels, which have no database and their own password but are
+// secured like the master.
+func sentinelDialOptions() ([]redis.DialOption, error) {
+	redisConfig := config.AppConfig.StandaloneRedisConfig
+	options := []redis.DialOption{
+		redis.DialConnectTimeout(redisConfig.RedisConnectTimeout),
+		redis.DialReadTimeout(redisConfig.RedisReadTimeout),
+		redis.DialWriteTimeout(redisConfig.RedisWriteTimeout),
+	}
+	if password := config.AppConfig.SentinelRedisConfig.Password; password != "" {
+		options = append(options, redis.DialPassword(password))
+	}
+	tlsOptions, err := tlsDialOptions()
+	if err != nil {
+		return nil, err
+	}
+	return append(options, tlsOptions...), nil
+}
+
+// dialOptions authenticates, selects the database and secures connections as configured.
+func dialOptions() ([]redis.DialOption, error) {
+	redisConfig := config.AppConfig.StandaloneRedisConfig
+	options := []redis.DialOption{
+		redis.DialConnectTimeout(redisConfig.RedisConnectTimeout),
+		redis.DialReadTimeout(redisConfig.RedisReadTimeout),
+		redis.DialWriteTimeout(redisConfig.RedisWriteTimeout),
+		redis.DialDatabase(redisConfig.RedisDB),
+	}
+	if redisConfig.RedisUsername != "" {
+		options = append(options, redis.DialUsername(redisConfig.RedisUsername))
+	}
+	if redisConfig.RedisPassword != "" {
+		options = append(options, redis.DialPassword(redisConfig.RedisPassword))
+	}
+	tlsOptions, err := tlsDialOptions()
+	if err != nil {
+		return nil, err
+	}
+	return append(options, tlsOptions...), nil
+}
+
+// tlsDialOptions secures connections with TLS if configured, trusting the configured CA.
+func tlsDialOptions() ([]redis.DialOption, error) {
+	redisConfig := config.AppConfig.StandaloneRedisConfig
+	if !redisConfig.RedisTLS {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if redisConfig.RedisTLSCACert != "" {
+		pem, err := os.ReadFile(redisConfig.RedisTLSCACert)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read redis CA certificate: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificate found in redis CA certificate file")
+		}
+	}
+	return []redis.DialOption{redis.DialUseTLS(true), redis.DialTLSConfig(tlsConfig)}, nil
 }
@@ -0,0 +1,215 @@
+package redisclient
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"swilly-delivery-service/config"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadConfig(t *testing.T, env map[string]string) {
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	t.Setenv("DIRECTORY_PATH", t.TempDir())
+	_, err := config.LoadAndGetConfig()
+	require.NoError(t, err)
+}
+
+func TestNewRedisPool_Auth(t *testing.T) {
+	server := miniredis.RunT(t)
+	server.RequireUserAuth("delivery", "secret")
+	loadConfig(t, map[string]string{
+		"STANDALONE_REDIS_HOST":     server.Addr(),
+		"STANDALONE_REDIS_USERNAME": "delivery",
+		"STANDALONE_REDIS_PASSWORD": "secret",
+		"STANDALONE_REDIS_DB":       "3",
+	})
+
+	pool, err := NewRedisPool()
+	require.NoError(t, err)
+	conn := pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("SET", "key", "value")
+	require.NoError(t, err)
+	assert.True(t, server.DB(3).Exists("key"))
+}
+
+func TestNewRedisPool_InvalidCACert(t *testing.T) {
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caCert, []byte("not a certificate"), 0644))
+	loadConfig(t, map[string]string{
+		"STANDALONE_REDIS_TLS":         "true",
+		"STANDALONE_REDIS_TLS_CA_CERT": caCert,
+	})
+
+	_, err := NewRedisPool()
+	assert.Error(t, err)
+}
+
+// fakeSentinel answers get-master-addr-by-name with master and announces failovers to its subscribers.
+type fakeSentinel struct {
+	listener    net.Listener
+	mutex       sync.Mutex
+	master      string
+	subscribers []net.Conn
+}
+
+func runFakeSentinel(t *testing.T, master string) *fakeSentinel {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSentinel{listener: listener, master: master}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeSentinel) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			s.unsubscribe(conn)
+			return
+		}
+
+		s.mutex.Lock()
+		switch strings.ToUpper(args[0]) {
+		case "SENTINEL":
+			host, port, _ := net.SplitHostPort(s.master)
+			fmt.Fprintf(conn, "*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(host), host, len(port), port)
+		case "SUBSCRIBE":
+			fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:1\r\n", len(args[1]), args[1])
+			s.subscribers = append(s.subscribers, conn)
+		default:
+			fmt.Fprint(conn, "+OK\r\n")
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func (s *fakeSentinel) unsubscribe(conn net.Conn) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, subscriber := range s.subscribers {
+		if subscriber == conn {
+			s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *fakeSentinel) subscribed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.subscribers) > 0
+}
+
+func (s *fakeSentinel) failover(masterName, master string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	oldHost, oldPort, _ := net.SplitHostPort(s.master)
+	newHost, newPort, _ := net.SplitHostPort(master)
+	s.master = master
+	event := strings.Join([]string{masterName, oldHost, oldPort, newHost, newPort}, " ")
+	for _, conn := range s.subscribers {
+		fmt.Fprintf(conn, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n",
+			len(switchMasterChannel), switchMasterChannel, len(event), event)
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestNewRedisPool_SentinelFailover(t *testing.T) {
+	oldMaster := miniredis.RunT(t)
+	newMaster := miniredis.RunT(t)
+	sentinel := runFakeSentinel(t, oldMaster.Addr())
+	loadConfig(t, map[string]string{
+		"REDIS_MODE":                 "sentinel",
+		"SENTINEL_REDIS_ADDRS":       "127.0.0.1:1, " + sentinel.listener.Addr().String(),
+		"SENTINEL_REDIS_MASTER_NAME": "delivery",
+	})
+
+	pool, err := NewRedisPool()
+	require.NoError(t, err)
+	set := func(key string) error {
+		conn := pool.Get()
+		defer conn.Close()
+		_, err := conn.Do("SET", key, "value")
+		return err
+	}
+
+	require.NoError(t, set("before"))
+	assert.True(t, oldMaster.Exists("before"))
+	require.Eventually(t, sentinel.subscribed, time.Second, 10*time.Millisecond)
+
+	sentinel.failover("delivery", newMaster.Addr())
+	require.Eventually(t, func() bool {
+		return set("after") == nil && newMaster.Exists("after")
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, oldMaster.Exists("after"))
+}
+
+func TestNewRedisPool_SentinelStopsWatchingOnClose(t *testing.T) {
+	master := miniredis.RunT(t)
+	sentinel := runFakeSentinel(t, master.Addr())
+	loadConfig(t, map[string]string{
+		"REDIS_MODE":                 "sentinel",
+		"SENTINEL_REDIS_ADDRS":       sentinel.listener.Addr().String(),
+		"SENTINEL_REDIS_MASTER_NAME": "delivery",
+	})
+
+	pool, err := NewRedisPool()
+	require.NoError(t, err)
+	require.Eventually(t, sentinel.subscribed, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, pool.Close())
+	require.Eventually(t, func() bool { return !sentinel.subscribed() }, time.Second, 10*time.Millisecond)
+	// Not subscribing again after the retry delay
+	time.Sleep(1100 * time.Millisecond)
+	assert.False(t, sentinel.subscribed())
+}
@@ -0,0 +1,228 @@
+package redisclient
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"swilly-delivery-service/internal/pkg/log"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"go.uber.org/zap"
+)
+
+const switchMasterChannel = "+switch-master"
+
+var errMasterChanged = errors.New("redis master changed")
+
+// sentinel discovers the master of a redis sentinel deployment and follows it across failovers.
+type sentinel struct {
+	addrs      []string
+	masterName string
+	options    []redis.DialOption
+
+	mutex  sync.RWMutex
+	master string
+
+	// done is closed to stop watching, along with subscription, the connection watch is subscribed with
+	done             chan struct{}
+	closeOnce        sync.Once
+	subscriptionLock sync.Mutex
+	subscription     redis.Conn
+}
+
+func newSentinel(addrs []string, masterName string, options ...redis.DialOption) *sentinel {
+	return &sentinel{addrs: addrs, masterName: masterName, options: options, done: make(chan struct{})}
+}
+
+// masterAddr returns the address of the master, asking the sentinels unless already known.
+func (s *sentinel) masterAddr() (string, error) {
+	s.mutex.RLock()
+	master := s.master
+	s.mutex.RUnlock()
+	if master != "" {
+		return master, nil
+	}
+	return s.discover()
+}
+
+// discover asks the sentinels in turn for the address of the master.
+func (s *sentinel) discover() (string, error) {
+	var errs []error
+	for _, addr := range s.addrs {
+		master, err := s.askMaster(addr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+			continue
+		}
+		s.setMaster(master)
+		return master, nil
+	}
+	return "", fmt.Errorf("no sentinel knows the address of master %s: %w", s.masterName, errors.Join(errs...))
+}
+
+func (s *sentinel) askMaster(addr string) (string, error) {
+	conn, err := redis.Dial("tcp", addr, s.options...)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	reply, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", s.masterName))
+	if err != nil {
+		return "", err
+	}
+	if len(reply) != 2 {
+		return "", fmt.Errorf("unexpected reply %v", reply)
+	}
+	return net.JoinHostPort(reply[0], reply[1]), nil
+}
+
+func (s *sentinel) setMaster(master string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.master != master {
+		log.Info("Using redis master", zap.String("master", master), zap.String("previous", s.master))
+		s.master = master
+	}
+}
+
+// forget drops the known master address so that the next connection asks the sentinels again.
+func (s *sentinel) forget(master string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.master == master {
+		s.master = ""
+	}
+}
+
+// watch follows the failovers announced by the sentinels until the sentinel is closed.
+func (s *sentinel) watch() {
+	for {
+		for _, addr := range s.addrs {
+			err := s.subscribe(addr)
+			if s.closed() {
+				return
+			}
+			if err != nil {
+				log.Error("Lost redis sentinel subscription", zap.String("sentinel", addr), zap.Error(err))
+			}
+		}
+		select {
+		case <-s.done:
+			return
+		case <-time.After(time.Second):
+		}
+
+		// Failovers may have happened while not subscribed
+		if _, err := s.discover(); err != nil {
+			log.Error("unable to discover redis master", zap.Error(err))
+		}
+	}
+}
+
+// close stops watching the sentinels, interrupting the subscription in progress.
+func (s *sentinel) close() {
+	s.closeOnce.Do(func() { close(s.done) })
+
+	s.subscriptionLock.Lock()
+	defer s.subscriptionLock.Unlock()
+	if s.subscription != nil {
+		s.subscription.Close()
+	}
+}
+
+func (s *sentinel) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *sentinel) subscribe(addr string) error {
+	conn, err := redis.Dial("tcp", addr, s.options...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Registered under the lock for close to interrupt the subscription, unless it closed before
+	s.subscriptionLock.Lock()
+	if s.closed() {
+		s.subscriptionLock.Unlock()
+		return nil
+	}
+	s.subscription = conn
+	s.subscriptionLock.Unlock()
+	defer func() {
+		s.subscriptionLock.Lock()
+		s.subscription = nil
+		s.subscriptionLock.Unlock()
+	}()
+
+	pubSub := redis.PubSubConn{Conn: conn}
+	if err := pubSub.Subscribe(switchMasterChannel); err != nil {
+		return err
+	}
+	for {
+		switch message := pubSub.ReceiveWithTimeout(0).(type) {
+		case redis.Message:
+			s.switchMaster(string(message.Data))
+		case error:
+			return message
+		}
+	}
+}
+
+// switchMaster handles a +switch-master event, formatted as
+// "<master name> <old ip> <old port> <new ip> <new port>".
+func (s *sentinel) switchMaster(event string) {
+	fields := strings.Fields(event)
+	if len(fields) != 5 || fields[0] != s.masterName {
+		return
+	}
+	s.setMaster(net.JoinHostPort(fields[3], fields[4]))
+}
+
+// masterConn is a connection to the master at addr.
+type masterConn struct {
+	redis.Conn
+	addr string
+}
+
+// dial connects to the current master.
+func (s *sentinel) dial(options ...redis.DialOption) (redis.Conn, error) {
+	master, err := s.masterAddr()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := redis.Dial("tcp", master, options...)
+	if err != nil {
+		// The master may have failed over without us hearing about it
+		s.forget(master)
+		return nil, err
+	}
+	return &masterConn{Conn: conn, addr: master}, nil
+}
+
+// testMaster tells connections to a former master apart, for the pool to close them.
+func (s *sentinel) testMaster(conn redis.Conn) error {
+	master, ok := conn.(*masterConn)
+	if !ok {
+		return nil
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if master.addr != s.master {
+		return errMasterChanged
+	}
+	return nil
+}
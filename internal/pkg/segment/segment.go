@@ -0,0 +1,84 @@
+package segment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownSegment = errors.New("unknown segment")
+	errInvalidSegment = errors.New("invalid segment ID")
+)
+
+// Resolver streams the members of segments.
+type Resolver interface {
+	// Members returns the user IDs of the members of the segment, one per line. ErrUnknownSegment is
+	// returned for segments the resolver doesn't know of.
+	Members(segmentID string) (io.ReadCloser, error)
+}
+
+type httpResolver struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPResolver returns a Resolver streaming the members of a segment from GET
+// <baseURL>/segments/<segment ID>/members. timeout bounds the wait for the response to start, not the
+// time it takes to stream the members.
+func NewHTTPResolver(baseURL string, timeout time.Duration) Resolver {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+	return &httpResolver{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Transport: transport},
+	}
+}
+
+func (r *httpResolver) Members(segmentID string) (io.ReadCloser, error) {
+	resp, err := r.client.Get(r.baseURL + "/segments/" + url.PathEscape(segmentID) + "/members")
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSegment, segmentID)
+	default:
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("segment API responded %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+}
+
+type fileResolver struct {
+	dir string
+}
+
+// NewFileResolver returns a Resolver reading the members of a segment from the file of dir named after
+// the segment.
+func NewFileResolver(dir string) Resolver {
+	return &fileResolver{dir: dir}
+}
+
+func (r *fileResolver) Members(segmentID string) (io.ReadCloser, error) {
+	// Segment IDs come from dropped files, they must not reach out of dir
+	if segmentID == "" || segmentID == "." || segmentID == ".." || strings.ContainsAny(segmentID, `/\`) {
+		return nil, fmt.Errorf("%w: %q", errInvalidSegment, segmentID)
+	}
+
+	file, err := os.Open(filepath.Join(r.dir, segmentID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSegment, segmentID)
+	}
+	return file, err
+}
@@ -0,0 +1,61 @@
+package segment
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readMembers(t *testing.T, resolver Resolver, segmentID string) string {
+	members, err := resolver.Members(segmentID)
+	require.NoError(t, err)
+	defer members.Close()
+	data, err := io.ReadAll(members)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestFileResolver_Members(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "churn-risk-7d"), []byte("1\n2\n"), 0644))
+	resolver := NewFileResolver(dir)
+
+	assert.Equal(t, "1\n2\n", readMembers(t, resolver, "churn-risk-7d"))
+
+	_, err := resolver.Members("vip")
+	assert.True(t, errors.Is(err, ErrUnknownSegment))
+	for _, segmentID := range []string{"", "..", "../churn-risk-7d", `a\b`} {
+		_, err = resolver.Members(segmentID)
+		assert.True(t, errors.Is(err, errInvalidSegment), segmentID)
+	}
+}
+
+func TestHTTPResolver_Members(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/segments/churn%20risk/members":
+			_, _ = w.Write([]byte("1\n2\n"))
+		case "/segments/broken/members":
+			http.Error(w, "database unavailable", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	resolver := NewHTTPResolver(server.URL+"/", time.Second)
+
+	assert.Equal(t, "1\n2\n", readMembers(t, resolver, "churn risk"))
+
+	_, err := resolver.Members("vip")
+	assert.True(t, errors.Is(err, ErrUnknownSegment))
+	_, err = resolver.Members("broken")
+	assert.EqualError(t, err, "segment API responded 500 Internal Server Error: database unavailable")
+}
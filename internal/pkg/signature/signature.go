@@ -0,0 +1,131 @@
+package signature
+
+import (
+	"bufio"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Suffix names the detached signature of a file after the file.
+const Suffix = ".sig"
+
+var ErrInvalidSignature = errors.New("signature does not match any approved key")
+
+// Files are signed with Ed25519ph, over their SHA-512 digest, so that they can be verified without
+// holding them in memory.
+var options = &ed25519.Options{Hash: crypto.SHA512}
+
+// Keyring is the allowlist of the public keys files may be signed with, each naming its signer.
+type Keyring struct {
+	signers []signer
+}
+
+type signer struct {
+	identity string
+	key      ed25519.PublicKey
+}
+
+// LoadKeyring reads the keyring at path, see ParseKeyring.
+func LoadKeyring(path string) (*Keyring, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseKeyring(file)
+}
+
+// ParseKeyring reads a keyring written as one "<identity> <base64 public key>" line per signer. Blank
+// lines and lines starting with # are ignored.
+func ParseKeyring(r io.Reader) (*Keyring, error) {
+	keyring := &Keyring{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected an identity and a public key", line)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("line %d: invalid ed25519 public key for %s", line, fields[0])
+		}
+		keyring.signers = append(keyring.signers, signer{identity: fields[0], key: key})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keyring.signers) == 0 {
+		return nil, errors.New("no approved keys")
+	}
+	return keyring, nil
+}
+
+// Verify checks the base64 encoded signature of the content read from r against the approved keys and
+// returns the identity of the signer.
+func (k *Keyring) Verify(r io.Reader, signature []byte) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || len(decoded) != ed25519.SignatureSize {
+		return "", fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	digest, err := digestOf(r)
+	if err != nil {
+		return "", err
+	}
+
+	for _, signer := range k.signers {
+		if ed25519.VerifyWithOptions(signer.key, digest, decoded, options) == nil {
+			return signer.identity, nil
+		}
+	}
+	return "", ErrInvalidSignature
+}
+
+// Sign returns the base64 encoded signature of the content read from r.
+func Sign(key ed25519.PrivateKey, r io.Reader) ([]byte, error) {
+	digest, err := digestOf(r)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := key.Sign(nil, digest, options)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(signature) + "\n"), nil
+}
+
+// EncodePrivateKey encodes a private key as the base64 encoding of its seed.
+func EncodePrivateKey(key ed25519.PrivateKey) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(key.Seed()) + "\n")
+}
+
+// DecodePrivateKey decodes a private key encoded with EncodePrivateKey.
+func DecodePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("invalid ed25519 private key")
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// KeyringLine returns the line approving the public key of key for identity in a keyring.
+func KeyringLine(identity string, key ed25519.PrivateKey) string {
+	return identity + " " + base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+func digestOf(r io.Reader) ([]byte, error) {
+	hash := sha512.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
@@ -0,0 +1,57 @@
+package signature
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+	return key
+}
+
+func TestKeyring_Verify(t *testing.T) {
+	alice, bob, mallory := newKey(t), newKey(t), newKey(t)
+	keyring, err := ParseKeyring(strings.NewReader("# support team\n" + KeyringLine("alice", alice) + "\n\n" + KeyringLine("bob", bob) + "\n"))
+	require.NoError(t, err)
+
+	signature, err := Sign(bob, strings.NewReader("1\n2\n"))
+	require.NoError(t, err)
+	signer, err := keyring.Verify(strings.NewReader("1\n2\n"), signature)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", signer)
+
+	_, err = keyring.Verify(strings.NewReader("1\n2\n3\n"), signature)
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+
+	signature, err = Sign(mallory, strings.NewReader("1\n2\n"))
+	require.NoError(t, err)
+	_, err = keyring.Verify(strings.NewReader("1\n2\n"), signature)
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+
+	_, err = keyring.Verify(strings.NewReader("1\n2\n"), []byte("not a signature"))
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+}
+
+func TestParseKeyring_Invalid(t *testing.T) {
+	for _, keyring := range []string{"", "# nobody\n", "alice\n", "alice c2hvcnQ=\n", "alice bob carol\n"} {
+		_, err := ParseKeyring(strings.NewReader(keyring))
+		assert.Error(t, err, keyring)
+	}
+}
+
+func TestPrivateKeyEncoding(t *testing.T) {
+	key := newKey(t)
+	decoded, err := DecodePrivateKey(EncodePrivateKey(key))
+	require.NoError(t, err)
+	assert.Equal(t, key, decoded)
+
+	_, err = DecodePrivateKey([]byte("c2hvcnQ="))
+	assert.Error(t, err)
+}
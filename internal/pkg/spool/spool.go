@@ -0,0 +1,186 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"swilly-delivery-service/internal/pkg/log"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Entry is a job that could not be enqueued.
+type Entry struct {
+	JobName   string                 `json:"job_name"`
+	Args      map[string]interface{} `json:"args"`
+	SpooledAt time.Time              `json:"spooled_at"`
+}
+
+// Spool keeps jobs on local disk until they can be enqueued.
+type Spool interface {
+	// Append durably adds an entry at the end of the spool.
+	Append(entry Entry) error
+	// AppendBatch durably adds entries at the end of the spool, syncing the disk once for all of them.
+	AppendBatch(entries []Entry) error
+	// Replay pushes the spooled entries in order, keeping the ones from the first that could not
+	// be pushed on. It returns the number of entries pushed and the error that stopped it if any.
+	Replay(push func(Entry) error) (int, error)
+}
+
+// offsetWidth is the fixed width of the offset recorded for a replay, so that it can be overwritten in place.
+const offsetWidth = 20
+
+type fileSpool struct {
+	path string
+	// mutex guards the spool file, replayMutex makes replays run one at a time
+	mutex       sync.Mutex
+	replayMutex sync.Mutex
+}
+
+// NewFileSpool returns a Spool writing one JSON entry per line to an append-only file at path.
+func NewFileSpool(path string) (Spool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return &fileSpool{path: path}, nil
+}
+
+func (s *fileSpool) Append(entry Entry) error {
+	return s.AppendBatch([]Entry{entry})
+}
+
+func (s *fileSpool) AppendBatch(entries []Entry) error {
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Replay pushes the entries of the spool in order. They're replayed from a file of their own so that new
+// ones can be appended meanwhile, recording the offset of the next entry to push next to it. When a push
+// fails or the process dies, the next replay carries on from that offset before the entries appended since,
+// so that entries already pushed are not pushed again.
+func (s *fileSpool) Replay(push func(Entry) error) (int, error) {
+	s.replayMutex.Lock()
+	defer s.replayMutex.Unlock()
+
+	var replayed int
+	for {
+		replaying, err := s.startReplay()
+		if errors.Is(err, os.ErrNotExist) {
+			return replayed, nil
+		}
+		if err != nil {
+			return replayed, err
+		}
+
+		pushed, err := s.replayFile(replaying, push)
+		replayed += pushed
+		if err != nil {
+			return replayed, err
+		}
+		if err := os.Remove(replaying + ".offset"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return replayed, err
+		}
+		if err := os.Remove(replaying); err != nil {
+			return replayed, err
+		}
+	}
+}
+
+// startReplay returns the file of the entries to replay, moving the spool to it unless one is left over
+// from the last replay.
+func (s *fileSpool) startReplay() (string, error) {
+	replaying := s.path + ".replaying"
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	_, err := os.Stat(replaying)
+	if errors.Is(err, os.ErrNotExist) {
+		err = os.Rename(s.path, replaying)
+	}
+	return replaying, err
+}
+
+func (s *fileSpool) replayFile(replaying string, push func(Entry) error) (int, error) {
+	file, err := os.Open(replaying)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	offsetFile, err := os.OpenFile(replaying+".offset", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer offsetFile.Close()
+
+	offset, err := readOffset(offsetFile)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var replayed int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// Most likely the last line, cut short by a crash while appending
+			log.Error("Skipping unreadable spool entry", zap.String("entry", scanner.Text()), zap.Error(err))
+		} else {
+			if err := push(entry); err != nil {
+				return replayed, err
+			}
+			replayed++
+		}
+
+		offset += int64(len(scanner.Bytes())) + 1
+		if _, err := offsetFile.WriteAt([]byte(fmt.Sprintf("%0*d", offsetWidth, offset)), 0); err != nil {
+			return replayed, err
+		}
+	}
+	return replayed, scanner.Err()
+}
+
+// readOffset returns the offset recorded in file, zero if it's empty.
+func readOffset(file *os.File) (int64, error) {
+	data := make([]byte, offsetWidth)
+	n, err := io.ReadFull(file, data)
+	if errors.Is(err, io.EOF) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(data[:n]), 10, 64)
+}
@@ -0,0 +1,95 @@
+package spool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSpool_Replay(t *testing.T) {
+	spool, err := NewFileSpool(filepath.Join(t.TempDir(), "spool", "jobs.jsonl"))
+	require.NoError(t, err)
+
+	for _, userID := range []string{"1", "2", "3"} {
+		require.NoError(t, spool.Append(Entry{JobName: "send_message", Args: map[string]interface{}{"userID": userID}}))
+	}
+
+	// Redis goes down again after the first entry
+	var pushed []string
+	replayed, err := spool.Replay(func(entry Entry) error {
+		if len(pushed) == 1 {
+			return errors.New("connection refused")
+		}
+		pushed = append(pushed, entry.Args["userID"].(string))
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, replayed)
+
+	require.NoError(t, spool.Append(Entry{JobName: "send_message", Args: map[string]interface{}{"userID": "4"}}))
+
+	replayed, err = spool.Replay(func(entry Entry) error {
+		pushed = append(pushed, entry.Args["userID"].(string))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, replayed)
+	assert.Equal(t, []string{"1", "2", "3", "4"}, pushed)
+
+	replayed, err = spool.Replay(func(Entry) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 0, replayed)
+}
+
+func TestFileSpool_ReplaySkipsTruncatedEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	spool, err := NewFileSpool(path)
+	require.NoError(t, err)
+	require.NoError(t, spool.Append(Entry{JobName: "send_message", Args: map[string]interface{}{"userID": "1"}}))
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"job_name":"send_mes`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	replayed, err := spool.Replay(func(Entry) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 1, replayed)
+}
+
+func TestFileSpool_ReplayResumesAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	spool, err := NewFileSpool(path)
+	require.NoError(t, err)
+	require.NoError(t, spool.AppendBatch([]Entry{
+		{JobName: "send_message", Args: map[string]interface{}{"userID": "1"}},
+		{JobName: "send_message", Args: map[string]interface{}{"userID": "2"}},
+		{JobName: "send_message", Args: map[string]interface{}{"userID": "3"}},
+	}))
+
+	// The process dies while pushing the second entry
+	var pushed []string
+	_, err = spool.Replay(func(entry Entry) error {
+		if len(pushed) == 1 {
+			return errors.New("killed")
+		}
+		pushed = append(pushed, entry.Args["userID"].(string))
+		return nil
+	})
+	assert.Error(t, err)
+
+	restarted, err := NewFileSpool(path)
+	require.NoError(t, err)
+	replayed, err := restarted.Replay(func(entry Entry) error {
+		pushed = append(pushed, entry.Args["userID"].(string))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, replayed)
+	assert.Equal(t, []string{"1", "2", "3"}, pushed)
+}
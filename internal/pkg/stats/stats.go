@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const keyPrefix = "stats:"
+
+// Store keeps named counters grouped by scope, e.g. a processed file or a campaign.
+type Store interface {
+	Incr(scope, field string, delta int64) error
+	Get(scope string) (map[string]int64, error)
+}
+
+type redisStore struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedisStore returns a Store that keeps each scope as a redis hash.
+func NewRedisStore(pool *redis.Pool, prefix string) Store {
+	return &redisStore{pool: pool, prefix: prefix}
+}
+
+func (s *redisStore) Incr(scope, field string, delta int64) error {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("HINCRBY", s.prefix+keyPrefix+scope, field, delta)
+	return err
+}
+
+func (s *redisStore) Get(scope string) (map[string]int64, error) {
+	conn := s.pool.Get()
+	defer conn.Close()
+
+	values, err := redis.Int64Map(conn.Do("HGETALL", s.prefix+keyPrefix+scope))
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+type memoryStore struct {
+	mutex  sync.Mutex
+	scopes map[string]map[string]int64
+}
+
+// NewMemoryStore returns a Store kept in memory, for running without redis. It's lost when the process exits.
+func NewMemoryStore() Store {
+	return &memoryStore{scopes: make(map[string]map[string]int64)}
+}
+
+func (s *memoryStore) Incr(scope, field string, delta int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	counters, ok := s.scopes[scope]
+	if !ok {
+		counters = make(map[string]int64)
+		s.scopes[scope] = counters
+	}
+	counters[field] += delta
+	return nil
+}
+
+func (s *memoryStore) Get(scope string) (map[string]int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	values := make(map[string]int64, len(s.scopes[scope]))
+	for field, value := range s.scopes[scope] {
+		values[field] = value
+	}
+	return values, nil
+}
+
+// FileScope is the scope under which counters for a processed file are kept.
+func FileScope(filename string) string {
+	return "file:" + filename
+}
+
+// CampaignScope is the scope under which delivery outcomes of a campaign are kept.
+func CampaignScope(campaign string) string {
+	return "campaign:" + campaign
+}
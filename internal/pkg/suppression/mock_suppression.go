@@ -0,0 +1,118 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: swilly-delivery-service/internal/pkg/suppression (interfaces: List)
+
+// Package suppression is a generated GoMock package.
+package suppression
+
+import (
+	io "io"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockList is a mock of List interface.
+type MockList struct {
+	ctrl     *gomock.Controller
+	recorder *MockListMockRecorder
+}
+
+// MockListMockRecorder is the mock recorder for MockList.
+type MockListMockRecorder struct {
+	mock *MockList
+}
+
+// NewMockList creates a new mock instance.
+func NewMockList(ctrl *gomock.Controller) *MockList {
+	mock := &MockList{ctrl: ctrl}
+	mock.recorder = &MockListMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockList) EXPECT() *MockListMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockList) Add(arg0 ...string) (int, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Add", varargs...)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockListMockRecorder) Add(arg0 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockList)(nil).Add), arg0...)
+}
+
+// Contains mocks base method.
+func (m *MockList) Contains(arg0 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Contains", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Contains indicates an expected call of Contains.
+func (mr *MockListMockRecorder) Contains(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Contains", reflect.TypeOf((*MockList)(nil).Contains), arg0)
+}
+
+// ContainsMany mocks base method.
+func (m *MockList) ContainsMany(arg0 []string) ([]bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ContainsMany", arg0)
+	ret0, _ := ret[0].([]bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ContainsMany indicates an expected call of ContainsMany.
+func (mr *MockListMockRecorder) ContainsMany(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ContainsMany", reflect.TypeOf((*MockList)(nil).ContainsMany), arg0)
+}
+
+// Import mocks base method.
+func (m *MockList) Import(arg0 io.Reader) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Import", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Import indicates an expected call of Import.
+func (mr *MockListMockRecorder) Import(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Import", reflect.TypeOf((*MockList)(nil).Import), arg0)
+}
+
+// Remove mocks base method.
+func (m *MockList) Remove(arg0 ...string) (int, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{}
+	for _, a := range arg0 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Remove", varargs...)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockListMockRecorder) Remove(arg0 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockList)(nil).Remove), arg0...)
+}
@@ -0,0 +1,244 @@
+package suppression
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"swilly-delivery-service/internal/pkg/recipient"
+	"sync"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	listKey         = "suppressed_users"
+	importBatchSize = 1000
+)
+
+// ErrInvalidUserID is returned for user IDs that don't follow the recipient ID rule.
+var ErrInvalidUserID = errors.New("invalid user ID")
+
+// List is the global opt-out list. User IDs present in it must never be messaged.
+type List interface {
+	Add(userIDs ...string) (int, error)
+	Remove(userIDs ...string) (int, error)
+	Contains(userID string) (bool, error)
+	// ContainsMany tells for each of userIDs whether it's in the list, in one round trip.
+	ContainsMany(userIDs []string) ([]bool, error)
+	Import(r io.Reader) (int, error)
+}
+
+type redisList struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedisList returns a List backed by a redis set.
+func NewRedisList(pool *redis.Pool, prefix string) List {
+	return &redisList{pool: pool, prefix: prefix}
+}
+
+// Add suppresses the given user IDs and returns how many were newly added.
+func (l *redisList) Add(userIDs ...string) (int, error) {
+	return l.update("SADD", userIDs)
+}
+
+// Remove lifts the suppression for the given user IDs and returns how many were removed.
+func (l *redisList) Remove(userIDs ...string) (int, error) {
+	return l.update("SREM", userIDs)
+}
+
+func (l *redisList) Contains(userID string) (bool, error) {
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	return redis.Bool(conn.Do("SISMEMBER", l.prefix+listKey, userID))
+}
+
+func (l *redisList) ContainsMany(userIDs []string) ([]bool, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	members, err := redis.Ints(conn.Do("SMISMEMBER", redis.Args{}.Add(l.prefix+listKey).AddFlat(userIDs)...))
+	if err != nil {
+		return nil, err
+	}
+	contained := make([]bool, len(members))
+	for i, member := range members {
+		contained[i] = member == 1
+	}
+	return contained, nil
+}
+
+// Import reads one user ID per line and adds them to the list in batches.
+func (l *redisList) Import(r io.Reader) (int, error) {
+	return importUserIDs(l, r)
+}
+
+func (l *redisList) update(command string, userIDs []string) (int, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	conn := l.pool.Get()
+	defer conn.Close()
+
+	return redis.Int(conn.Do(command, redis.Args{}.Add(l.prefix+listKey).AddFlat(userIDs)...))
+}
+
+type memoryList struct {
+	mutex   sync.RWMutex
+	userIDs map[string]struct{}
+}
+
+// NewMemoryList returns a List kept in memory, for running without redis. It's lost when the process exits.
+func NewMemoryList() List {
+	return &memoryList{userIDs: make(map[string]struct{})}
+}
+
+func (l *memoryList) Add(userIDs ...string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	added := 0
+	for _, userID := range userIDs {
+		if _, ok := l.userIDs[userID]; !ok {
+			l.userIDs[userID] = struct{}{}
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (l *memoryList) Remove(userIDs ...string) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	removed := 0
+	for _, userID := range userIDs {
+		if _, ok := l.userIDs[userID]; ok {
+			delete(l.userIDs, userID)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+func (l *memoryList) Contains(userID string) (bool, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	_, ok := l.userIDs[userID]
+	return ok, nil
+}
+
+func (l *memoryList) ContainsMany(userIDs []string) ([]bool, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	contained := make([]bool, len(userIDs))
+	for i, userID := range userIDs {
+		_, contained[i] = l.userIDs[userID]
+	}
+	return contained, nil
+}
+
+func (l *memoryList) Import(r io.Reader) (int, error) {
+	return importUserIDs(l, r)
+}
+
+// importUserIDs reads one user ID per line and adds them to list in batches.
+func importUserIDs(list List, r io.Reader) (int, error) {
+	added := 0
+	batch := make([]string, 0, importBatchSize)
+	flush := func() error {
+		n, err := list.Add(batch...)
+		added += n
+		batch = batch[:0]
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		userID := strings.TrimSpace(scanner.Text())
+		if userID == "" {
+			continue
+		}
+		batch = append(batch, userID)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return added, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return added, err
+	}
+	if err := flush(); err != nil {
+		return added, err
+	}
+	return added, nil
+}
+
+type validatingList struct {
+	List
+	validator recipient.Validator
+}
+
+// NewValidatingList returns a List normalizing user IDs the way they're read from files before handing
+// them to list, so that suppressed users match those of files, and refusing the IDs validator rejects.
+func NewValidatingList(list List, validator recipient.Validator) List {
+	return &validatingList{List: list, validator: validator}
+}
+
+func (l *validatingList) Add(userIDs ...string) (int, error) {
+	normalized, err := l.normalize(userIDs)
+	if err != nil {
+		return 0, err
+	}
+	return l.List.Add(normalized...)
+}
+
+func (l *validatingList) Remove(userIDs ...string) (int, error) {
+	normalized, err := l.normalize(userIDs)
+	if err != nil {
+		return 0, err
+	}
+	return l.List.Remove(normalized...)
+}
+
+func (l *validatingList) Contains(userID string) (bool, error) {
+	return l.List.Contains(recipient.Normalize(userID))
+}
+
+func (l *validatingList) ContainsMany(userIDs []string) ([]bool, error) {
+	normalized := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		normalized[i] = recipient.Normalize(userID)
+	}
+	return l.List.ContainsMany(normalized)
+}
+
+// Import adds the user IDs read from r in batches, stopping at the first invalid one. The batches
+// before it are kept, importing the same IDs again once fixed is harmless.
+func (l *validatingList) Import(r io.Reader) (int, error) {
+	return importUserIDs(l, r)
+}
+
+func (l *validatingList) normalize(userIDs []string) ([]string, error) {
+	normalized := make([]string, 0, len(userIDs))
+	for _, userID := range userIDs {
+		userID = recipient.Normalize(userID)
+		if err := l.validator.Validate(userID); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUserID, err)
+		}
+		normalized = append(normalized, userID)
+	}
+	return normalized, nil
+}
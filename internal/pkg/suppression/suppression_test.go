@@ -0,0 +1,127 @@
+package suppression
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"swilly-delivery-service/internal/pkg/recipient"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestList(t *testing.T) List {
+	server := miniredis.RunT(t)
+	pool := &redis.Pool{
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", server.Addr())
+		},
+	}
+	return NewRedisList(pool, "delivery:")
+}
+
+func TestRedisList_AddRemoveContains(t *testing.T) {
+	list := newTestList(t)
+
+	added, err := list.Add("1", "2", "2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	suppressed, err := list.Contains("2")
+	require.NoError(t, err)
+	assert.True(t, suppressed)
+
+	removed, err := list.Remove("2", "3")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	suppressed, err = list.Contains("2")
+	require.NoError(t, err)
+	assert.False(t, suppressed)
+
+	contained, err := list.ContainsMany([]string{"2", "1", "3", "1"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{false, true, false, true}, contained)
+}
+
+func TestRedisList_AddNothing(t *testing.T) {
+	list := newTestList(t)
+
+	added, err := list.Add()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, added)
+}
+
+func TestRedisList_Import(t *testing.T) {
+	list := newTestList(t)
+
+	var ids strings.Builder
+	for i := 0; i < importBatchSize+5; i++ {
+		ids.WriteString(strconv.Itoa(i) + "\n")
+	}
+	ids.WriteString("\n  2000  \n")
+
+	imported, err := list.Import(strings.NewReader(ids.String()))
+	require.NoError(t, err)
+	assert.Equal(t, importBatchSize+6, imported)
+
+	suppressed, err := list.Contains("2000")
+	require.NoError(t, err)
+	assert.True(t, suppressed)
+}
+
+func TestMemoryList(t *testing.T) {
+	list := NewMemoryList()
+
+	added, err := list.Add("1", "2", "2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+
+	removed, err := list.Remove("2", "3")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	imported, err := list.Import(strings.NewReader("1\n\n  3  \n"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, imported)
+
+	for userID, expected := range map[string]bool{"1": true, "2": false, "3": true} {
+		suppressed, err := list.Contains(userID)
+		require.NoError(t, err)
+		assert.Equal(t, expected, suppressed, userID)
+	}
+
+	contained, err := list.ContainsMany([]string{"1", "2", "3"})
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true}, contained)
+}
+
+func TestValidatingList(t *testing.T) {
+	validator, err := recipient.NewIntValidator("1", "")
+	require.NoError(t, err)
+	list := NewValidatingList(NewMemoryList(), validator)
+
+	added, err := list.Add(" 123 ", "\ufeff456\r")
+	require.NoError(t, err)
+	assert.Equal(t, 2, added)
+	suppressed, err := list.Contains("123")
+	require.NoError(t, err)
+	assert.True(t, suppressed)
+
+	_, err = list.Add("789", "abc")
+	assert.True(t, errors.Is(err, ErrInvalidUserID))
+	suppressed, err = list.Contains("789")
+	require.NoError(t, err)
+	assert.False(t, suppressed)
+
+	imported, err := list.Import(strings.NewReader("789\n0\n"))
+	assert.True(t, errors.Is(err, ErrInvalidUserID))
+	assert.Zero(t, imported)
+
+	removed, err := list.Remove("456 ")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"swilly-delivery-service/config"
+	"swilly-delivery-service/internal/app"
 	"swilly-delivery-service/internal/app/server"
 	"swilly-delivery-service/internal/app/worker"
+	"swilly-delivery-service/internal/pkg/signature"
+	"swilly-delivery-service/internal/pkg/suppression"
 
 	"github.com/urfave/cli/v2"
 )
@@ -19,31 +28,266 @@ import (
 //	@BasePath /
 //	@query.collection.format multi
 func main() {
-	app := cli.NewApp()
-	app.Name = "swilly-delivery-service"
-	app.Version = "1.0.0"
-	app.Commands = []*cli.Command{
+	cliApp := cli.NewApp()
+	cliApp.Name = "swilly-delivery-service"
+	cliApp.Version = "1.0.0"
+	cliApp.Commands = []*cli.Command{
 		{
 			Name:  "worker",
 			Usage: "Worker mode for Swilly Delivery Service",
 			Action: func(context *cli.Context) error {
 				log.Println("Starting worker mode")
-				return worker.StartWorker(context.Context)
+				if err := app.Bootstrap(); err != nil {
+					return err
+				}
+				return run(context, false, true)
 			},
 		},
 		{
 			Name:        "server",
-			Description: "Server mode for Swilly Delivery Service",
+			Description: "Server mode for Swilly Delivery Service, also running the worker when WORKER_ENABLED is set",
 			Action: func(context *cli.Context) error {
 				log.Println("Starting server mode")
-				server.StartServer()
-				return nil
+				if err := app.Bootstrap(); err != nil {
+					return err
+				}
+				return run(context, true, config.AppConfig.WorkerEnabled)
+			},
+		},
+		{
+			Name:  "all",
+			Usage: "Run the server and the worker in one process",
+			Action: func(context *cli.Context) error {
+				log.Println("Starting server and worker")
+				if err := app.Bootstrap(); err != nil {
+					return err
+				}
+				return run(context, true, true)
+			},
+		},
+		{
+			Name:      "validate",
+			Usage:     "Dry run a file through ingestion without sending anything",
+			ArgsUsage: "<file>",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "report",
+					Usage: "where to write the rejected lines report, defaults to <file>.report.csv",
+				},
+			},
+			Action: validateFile,
+		},
+		{
+			Name:  "signature",
+			Usage: "Sign files for the approved signers check",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "keygen",
+					Usage:     "Generate a signing key, written to <identity>.key, and print its keyring line",
+					ArgsUsage: "<identity>",
+					Action:    generateSigningKey,
+				},
+				{
+					Name:      "sign",
+					Usage:     "Sign the given files, writing each signature to <file>.sig",
+					ArgsUsage: "<file>...",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "key",
+							Usage:    "the signing key generated with keygen",
+							Required: true,
+						},
+					},
+					Action: signFiles,
+				},
+			},
+		},
+		{
+			Name:  "suppression",
+			Usage: "Manage the global suppression list",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "add",
+					Usage:     "Suppress the given user IDs",
+					ArgsUsage: "<userID>...",
+					Action: func(context *cli.Context) error {
+						return updateSuppressionList(context, func(list suppression.List, userIDs []string) (int, error) {
+							return list.Add(userIDs...)
+						})
+					},
+				},
+				{
+					Name:      "remove",
+					Usage:     "Lift the suppression for the given user IDs",
+					ArgsUsage: "<userID>...",
+					Action: func(context *cli.Context) error {
+						return updateSuppressionList(context, func(list suppression.List, userIDs []string) (int, error) {
+							return list.Remove(userIDs...)
+						})
+					},
+				},
+				{
+					Name:      "import",
+					Usage:     "Suppress every user ID in the given file, one per line",
+					ArgsUsage: "<file>",
+					Action: func(context *cli.Context) error {
+						if context.NArg() != 1 {
+							return errors.New("expected exactly one file")
+						}
+						file, err := os.Open(context.Args().First())
+						if err != nil {
+							return err
+						}
+						defer file.Close()
+
+						return updateSuppressionList(context, func(list suppression.List, _ []string) (int, error) {
+							return list.Import(file)
+						})
+					},
+				},
 			},
 		},
 	}
 
-	err := app.Run(os.Args)
+	err := cliApp.Run(os.Args)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// run runs the server and worker until the process is asked to stop, sharing the bootstrapped dependencies.
+func run(context *cli.Context, withServer, withWorker bool) error {
+	if config.AppConfig.WorkerPoolConfig.Backend == config.QueueBackendMemory && !(withServer && withWorker) {
+		// The jobs enqueued by a server would never reach a worker of another process
+		return errors.New("the memory queue backend only runs the server and the worker together, use the all command")
+	}
+
+	var services []app.Service
+	if withWorker {
+		services = append(services, worker.NewWorker(app.AppDependency))
+	}
+	if withServer {
+		srv, err := server.NewServer(app.AppDependency)
+		if err != nil {
+			return err
+		}
+		services = append(services, srv)
+	}
+	return app.Run(context.Context, services...)
+}
+
+func updateSuppressionList(context *cli.Context, apply func(suppression.List, []string) (int, error)) error {
+	if err := app.Bootstrap(); err != nil {
+		return err
+	}
+
+	updated, err := apply(app.AppDependency.Suppression, context.Args().Slice())
+	if err != nil {
+		return err
+	}
+	log.Printf("Updated %d user IDs in suppression list", updated)
+	return nil
+}
+
+func validateFile(context *cli.Context) error {
+	if context.NArg() != 1 {
+		return errors.New("expected exactly one file")
+	}
+	if err := app.Bootstrap(); err != nil {
+		return err
+	}
+
+	path := context.Args().First()
+	reportPath := context.String("report")
+	if reportPath == "" {
+		reportPath = path + ".report.csv"
+	}
+	report, err := os.Create(reportPath)
+	if err != nil {
+		return err
+	}
+	defer report.Close()
+
+	result, err := server.ValidateFile(context.Context, path, app.AppDependency.DryRun(), report)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func generateSigningKey(context *cli.Context) error {
+	if context.NArg() != 1 {
+		return errors.New("expected exactly one identity")
+	}
+	identity := context.Args().First()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return err
+	}
+
+	// O_EXCL, not to lose a key in use by generating another one over it
+	file, err := os.OpenFile(identity+".key", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(signature.EncodePrivateKey(key)); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	fmt.Println(signature.KeyringLine(identity, key))
+	return nil
+}
+
+func signFiles(context *cli.Context) error {
+	data, err := os.ReadFile(context.String("key"))
+	if err != nil {
+		return err
+	}
+	key, err := signature.DecodePrivateKey(data)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range context.Args().Slice() {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		sig, err := signature.Sign(key, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+		if err := writeSignature(path+signature.Suffix, sig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSignature writes sig to path through a temporary file, so that a server watching the directory
+// never reads it partly written.
+func writeSignature(path string, sig []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "signature-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(sig); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}